@@ -2,8 +2,14 @@ package main
 
 import (
 	"bitcoinrpcschema/internal/bitcoind"
+	"errors"
+	"flag"
+	"io/fs"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 const dbPath = "rpc.db"
@@ -11,7 +17,30 @@ const dbPath = "rpc.db"
 const daemonPath = "bitcoin-core"
 
 func main() {
-	db, err := bitcoind.CreateDb(daemonPath)
+	incremental := flag.Bool("incremental", false, "only capture releases missing from the existing DB, or built from a different binary")
+	workers := flag.Int("workers", 4, "number of bitcoind instances to run at once")
+	startupTimeout := flag.Duration("startup-timeout", time.Minute, "how long bitcoind has to start answering RPCs")
+	stopTimeout := flag.Duration("stop-timeout", 30*time.Second, "how long bitcoind has to exit before it is killed")
+	captureTimeout := flag.Duration("capture-timeout", 10*time.Minute, "how long capturing a single release may take")
+	flag.Parse()
+
+	// stop every bitcoind we started rather than leave them running
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		log.Println("interrupted, stopping bitcoind instances")
+		bitcoind.StopAll()
+		os.Exit(1)
+	}()
+
+	opts := bitcoind.CaptureOptions{
+		Workers:        *workers,
+		StartupTimeout: *startupTimeout,
+		StopTimeout:    *stopTimeout,
+		CaptureTimeout: *captureTimeout,
+	}
+	db, err := createDb(*incremental, opts)
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -20,3 +49,17 @@ func main() {
 		log.Fatalln(err)
 	}
 }
+
+func createDb(incremental bool, opts bitcoind.CaptureOptions) ([]byte, error) {
+	if !incremental {
+		return bitcoind.CreateDb(daemonPath, opts)
+	}
+	existing, err := os.ReadFile(dbPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return bitcoind.CreateDb(daemonPath, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bitcoind.UpdateDb(daemonPath, existing, opts)
+}
@@ -2,7 +2,12 @@ package main
 
 import (
 	"bitcoinrpcschema/internal/downloader"
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
 const rootPath = "bitcoin-core"
@@ -11,9 +16,63 @@ const binUrl = "https://bitcoincore.org/bin/"
 
 const gitUrl = "https://github.com/bitcoin/bitcoin.git"
 
+// how many major versions to download when no versions are selected
+const defaultLatestMajors = 3
+
 func main() {
-	err := downloader.Get(rootPath, binUrl, gitUrl)
+	var opts downloader.Options
+	flag.StringVar(&opts.KeyringPath, "keyring", "", "armored builder keys to verify SHA256SUMS with, a file or a directory such as guix.sigs/builder-keys")
+	flag.IntVar(&opts.MinSignatures, "min-signatures", 3, "how many builders from the keyring must have signed SHA256SUMS since v22, at least 1. Before, its only signature must be from the keyring")
+	flag.BoolVar(&opts.SkipSignatures, "skip-signatures", false, "only check tarballs against SHA256SUMS, without verifying its signatures")
+	flag.StringVar(&opts.CacheDir, "cache", defaultCacheDir(), "where tarballs, release metadata and the bitcoin repo mirror are kept between runs, empty to not cache")
+	flag.BoolVar(&opts.Offline, "offline", false, "only use what is in the cache, without network access")
+	versions := flag.String("versions", "", "comma separated versions to download, like 0.21.2,22.1,27.0rc1")
+	flag.StringVar(&opts.Select.Constraint, "constraint", "", "space separated version bounds, like \">=22 <28\"")
+	flag.IntVar(&opts.Select.LatestMajors, "latest-majors", 0, fmt.Sprintf("only the releases of the latest major versions, 0 for all (default %d without -versions or -constraint)", defaultLatestMajors))
+	flag.BoolVar(&opts.Select.LatestPatch, "latest-patch", false, "only the latest release of each major version")
+	flag.BoolVar(&opts.Select.ReleaseCandidates, "rc", false, "include release candidates, those listed in -versions always are")
+	flag.StringVar(&opts.Platform, "platform", "", fmt.Sprintf("platform of the releases, one of %s (default: this machine's)", strings.Join(downloader.Platforms, ", ")))
+	buildRef := flag.String("build-ref", "", "instead of downloading releases, build this ref of the -repo, like master or origin/29.x. Arguments after the flags are passed to cmake or ./configure")
+	repo := flag.String("repo", ".", "local bitcoin repo to build -build-ref from")
+	var buildOpts downloader.BuildOptions
+	flag.IntVar(&buildOpts.Jobs, "jobs", 0, "parallel build jobs, 0 for the number of CPUs")
+	flag.Parse()
+
+	if *buildRef != "" {
+		buildOpts.ConfigureArgs = flag.Args()
+		label, err := downloader.Build(rootPath, *repo, *buildRef, buildOpts)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		log.Printf("built %s into %s/bitcoin-%s", *buildRef, rootPath, label)
+		return
+	}
+
+	if *versions != "" {
+		opts.Select.Versions = strings.Split(*versions, ",")
+	}
+	if *versions == "" && opts.Select.Constraint == "" && !isFlagSet("latest-majors") {
+		opts.Select.LatestMajors = defaultLatestMajors
+	}
+
+	err := downloader.Get(rootPath, binUrl, gitUrl, opts)
 	if err != nil {
 		log.Fatalln(err)
 	}
 }
+
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "bitcoinrpcschema")
+}
+
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+	return set
+}
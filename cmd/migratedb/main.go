@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bitcoinrpcschema/internal/bitcoind"
+	"log"
+	"os"
+)
+
+const dbPath = "rpc.db"
+
+// rewrites the DB in the current format, e.g. to convert a gob DB to JSON
+func main() {
+	b, err := os.ReadFile(dbPath)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	db, err := bitcoind.ReadDb(b)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	b, err = db.Marshal()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = os.WriteFile(dbPath, b, 0644)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
@@ -0,0 +1,154 @@
+package bitcoind
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Argument is an RPC argument parsed from help text. Object and array arguments carry their members in Fields;
+// array elements have the placeholder shown in the help text as their Name.
+// Objects with UserKeys take keys chosen by the caller, and their Fields are examples of such entries.
+type Argument struct {
+	Name        string     `json:"name"`
+	Type        string     `json:"type"`
+	Required    bool       `json:"required,omitempty"`
+	Optional    bool       `json:"optional,omitempty"`
+	Default     string     `json:"default,omitempty"`
+	HasDefault  bool       `json:"hasDefault,omitempty"`
+	Description string     `json:"description"`
+	Fields      []Argument `json:"fields,omitempty"`
+	UserKeys    bool       `json:"userKeys,omitempty"`
+}
+
+// ParseArguments parses the "Arguments:" and "Named Arguments:" sections of an RPC help text.
+// Named-only arguments are attached as fields of the options object that accepts them, unless it already has them.
+func ParseArguments(help string) ([]Argument, error) {
+	sections, err := splitHelp(help)
+	if err != nil {
+		e := fmt.Errorf("failed to split help: %w", err)
+		return nil, e
+	}
+
+	var args, named []Argument
+	for _, sec := range sections {
+		switch sec.title {
+		case "Arguments":
+			args, err = parseArgumentSection(sec.lines)
+		case "Named Arguments":
+			named, err = parseArgumentSection(sec.lines)
+		}
+		if err != nil {
+			e := fmt.Errorf("failed to parse %s: %w", sec.title, err)
+			return nil, e
+		}
+	}
+
+	if len(named) > 0 {
+		options := namedArgumentsTarget(args)
+		if options == nil {
+			return nil, fmt.Errorf("found named arguments but no options argument to hold them")
+		}
+		for _, n := range named {
+			if !slices.ContainsFunc(options.Fields, func(f Argument) bool { return f.Name == n.Name }) {
+				options.Fields = append(options.Fields, n)
+			}
+		}
+	}
+	return args, nil
+}
+
+const namedArgumentsDescription = "Options object that can be used to pass named arguments"
+
+func namedArgumentsTarget(args []Argument) *Argument {
+	for i := range args {
+		if strings.HasPrefix(args[i].Description, namedArgumentsDescription) {
+			return &args[i]
+		}
+	}
+	return nil
+}
+
+var positionalNameRe = regexp.MustCompile(`^\d+\.\s+`)
+
+func parseArgumentSection(lines []string) ([]Argument, error) {
+	entries, err := parseHelpEntries(lines)
+	if err != nil {
+		return nil, err
+	}
+
+	var args []Argument
+	var stack []*Argument
+	for _, e := range entries {
+		if e.indent == 0 {
+			name := positionalNameRe.ReplaceAllString(e.left, "")
+			a := Argument{Name: strings.Trim(name, `"`)}
+			a.setMeta(e)
+			args = append(args, a)
+			stack = []*Argument{&args[len(args)-1]}
+			continue
+		}
+		if len(stack) == 0 {
+			return nil, fmt.Errorf("nested entry %q precedes any argument", e.left)
+		}
+
+		parent := stack[len(stack)-1]
+		switch {
+		case closesContainer(e.left):
+			stack = stack[:len(stack)-1]
+		case isElision(e.left):
+			if parent.Type == "json object" {
+				parent.UserKeys = true
+			}
+		case opensContainer(e.left) && !e.hasMeta && len(stack) == 1 && len(parent.Fields) == 0:
+			// the container of a top level object or array argument, whose type was given on the argument's own line
+			stack = append(stack, parent)
+		default:
+			key, rest := splitJsonKey(e.left)
+			a := Argument{Name: key}
+			if key == "" {
+				a.Name = strings.Trim(strings.TrimSuffix(rest, ","), `"{[`)
+			}
+			a.setMeta(e)
+			if a.Type == "" && strings.HasSuffix(rest, "{") {
+				a.Type = "json object"
+			} else if a.Type == "" && strings.HasSuffix(rest, "[") {
+				a.Type = "json array"
+			}
+			parent.Fields = append(parent.Fields, a)
+			if opensContainer(rest) {
+				stack = append(stack, &parent.Fields[len(parent.Fields)-1])
+			}
+		}
+		if len(stack) == 0 {
+			return nil, fmt.Errorf("unbalanced closing %q", e.left)
+		}
+	}
+	return args, nil
+}
+
+// setMeta fills in the argument from a "(type, required|optional, default=...)" annotation and description
+func (a *Argument) setMeta(e helpEntry) {
+	a.Description = e.description
+	if !e.hasMeta {
+		return
+	}
+	meta := e.meta
+	if i := strings.Index(meta, "default="); i >= 0 {
+		a.Default = meta[i+len("default="):]
+		a.HasDefault = true
+		meta = meta[:i]
+	}
+	for i, part := range strings.Split(meta, ",") {
+		part = strings.TrimSpace(part)
+		switch {
+		case i == 0:
+			a.Type = part
+		case part == "required":
+			a.Required = true
+		case part == "optional":
+			a.Optional = true
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package bitcoind
+
+import (
+	_ "embed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+//go:embed test/createrawtransaction.txt
+var createRawTransactionHelp string
+
+//go:embed test/getblock.txt
+var getBlockHelp string
+
+//go:embed test/send.txt
+var sendHelp string
+
+func TestParseArguments(t *testing.T) {
+	args, err := ParseArguments(getBlockHelp)
+	require.NoError(t, err)
+	expected := []Argument{
+		{Name: "blockhash", Type: "string", Required: true, Description: "The block hash"},
+		{Name: "verbosity|verbose", Type: "numeric", Optional: true, Default: "1", HasDefault: true, Description: "0 for hex-encoded data, 1 for a JSON object, 2 for JSON object with transaction data, and 3 for JSON object with transaction data including prevout information for inputs"},
+	}
+	assert.Equal(t, expected, args)
+}
+
+func TestParseNestedArguments(t *testing.T) {
+	args, err := ParseArguments(createRawTransactionHelp)
+	require.NoError(t, err)
+	require.Len(t, args, 4)
+
+	inputs := args[0]
+	assert.Equal(t, "inputs", inputs.Name)
+	assert.Equal(t, "json array", inputs.Type)
+	require.Len(t, inputs.Fields, 1)
+	input := inputs.Fields[0]
+	assert.Equal(t, "json object", input.Type)
+	require.Len(t, input.Fields, 3)
+	assert.Equal(t, Argument{Name: "txid", Type: "string", Required: true, Description: "The transaction id"}, input.Fields[0])
+	sequence := input.Fields[2]
+	assert.Equal(t, "sequence", sequence.Name)
+	assert.Equal(t, "depends on the value of the 'replaceable' and 'locktime' arguments", sequence.Default)
+
+	outputs := args[1]
+	assert.Equal(t, "The outputs specified as key-value pairs.\n"+
+		"Each key may only appear once, i.e. there can only be one 'data' output, and no address may be duplicated.\n"+
+		"At least one output of either type must be specified.\n"+
+		"For compatibility reasons, a dictionary, which holds the key-value pairs directly, is also\n"+
+		"accepted as second parameter.", outputs.Description)
+	require.Len(t, outputs.Fields, 2)
+	assert.True(t, outputs.Fields[0].UserKeys)
+	assert.False(t, outputs.Fields[1].UserKeys)
+	assert.Equal(t, "address", outputs.Fields[0].Fields[0].Name)
+	assert.Equal(t, "numeric or string", outputs.Fields[0].Fields[0].Type)
+	assert.Equal(t, "data", outputs.Fields[1].Fields[0].Name)
+
+	assert.Equal(t, Argument{Name: "locktime", Type: "numeric", Optional: true, Default: "0", HasDefault: true, Description: "Raw locktime. Non-0 value also locktime-activates inputs"}, args[2])
+	assert.Equal(t, "replaceable", args[3].Name)
+}
+
+func TestParseNamedArguments(t *testing.T) {
+	args, err := ParseArguments(sendHelp)
+	require.NoError(t, err)
+	require.Len(t, args, 3)
+
+	options := args[2]
+	require.Len(t, options.Fields, 3)
+	assert.Equal(t, "add_inputs", options.Fields[0].Name)
+	assert.Equal(t, `false when "inputs" are specified, true otherwise`, options.Fields[0].Default)
+	assert.Equal(t, "inputs", options.Fields[1].Name)
+	require.Len(t, options.Fields[1].Fields, 1)
+	assert.Equal(t, "txid", options.Fields[1].Fields[0].Fields[0].Name)
+	assert.Equal(t, "lock_unspents", options.Fields[2].Name)
+}
+
+func TestParseNamedArgumentsListedTwice(t *testing.T) {
+	help := "send ( options )\n\nArguments:\n" +
+		"1. options             (json object, optional) Options object that can be used to pass named arguments, listed below.\n" +
+		"     {\n" +
+		"       \"add_inputs\": bool, (boolean, optional, default=false) Automatically include coins\n" +
+		"     }\n\n" +
+		"Named Arguments:\n" +
+		"add_inputs             (boolean, optional, default=false) Automatically include coins\n" +
+		"lock_unspents          (boolean, optional, default=false) Lock selected unspent outputs\n"
+	args, err := ParseArguments(help)
+	require.NoError(t, err)
+	require.Len(t, args, 1)
+	require.Len(t, args[0].Fields, 2)
+	assert.Equal(t, "add_inputs", args[0].Fields[0].Name)
+	assert.Equal(t, "lock_unspents", args[0].Fields[1].Name)
+}
+
+func TestParseNamedArgumentsWithoutOptions(t *testing.T) {
+	help := "send ( conf_target )\n\nArguments:\n" +
+		"1. conf_target         (numeric, optional) Confirmation target in blocks\n\n" +
+		"Named Arguments:\n" +
+		"lock_unspents          (boolean, optional, default=false) Lock selected unspent outputs\n"
+	_, err := ParseArguments(help)
+	assert.ErrorContains(t, err, "no options argument")
+}
+
+func TestParseNoArguments(t *testing.T) {
+	args, err := ParseArguments("getblockcount\n\nReturns the height of the most-work fully-validated chain.\n\nResult:\nn    (numeric) The current block count\n")
+	require.NoError(t, err)
+	assert.Empty(t, args)
+}
@@ -1,89 +1,347 @@
 package bitcoind
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/rpcclient"
+	"log"
+	"net"
 	"os"
 	"os/exec"
+	"path"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 )
 
 type Config struct {
 	Client  *rpcclient.Client
 	Cleanup func()
+	// Fail attaches the tail of debug.log to an error from talking to the node
+	Fail func(err error) error
 }
 
-func startBitcoind(path string) (conf Config, err error) {
-	var tmpDirectory string
-	tmpDirectory, err = os.MkdirTemp("", "bitcoinrpcschema-bitcoind")
-	if err != nil {
-		return
+const (
+	defaultStartupTimeout = time.Minute
+	defaultStopTimeout    = 30 * time.Second
+	defaultCaptureTimeout = 10 * time.Minute
+	probeInterval         = 100 * time.Millisecond
+	startAttempts         = 3
+	logTailLines          = 20
+)
+
+// NodeError is an error from a bitcoind node, with the end of its debug.log
+type NodeError struct {
+	Err     error
+	LogTail string
+}
+
+func (e *NodeError) Error() string {
+	if e.LogTail == "" {
+		return e.Err.Error()
 	}
+	return fmt.Sprintf("%v\ndebug.log tail:\n%s", e.Err, e.LogTail)
+}
+
+func (e *NodeError) Unwrap() error {
+	return e.Err
+}
+
+// node is a supervised bitcoind process
+type node struct {
+	cmd     *exec.Cmd
+	dataDir string
+	rpcPort int
+	// exited is closed once the process has exited
+	exited    chan struct{}
+	stopOnce  sync.Once
+	closeOnce sync.Once
+	stopWait  time.Duration
+}
+
+// running holds every started node, so that they can be stopped if we are interrupted
+var running = struct {
+	sync.Mutex
+	nodes map[*node]struct{}
+}{nodes: make(map[*node]struct{})}
 
-	removeTempDir := func() {
-		removeErr := os.RemoveAll(tmpDirectory)
-		if removeErr != nil {
-			_, _ = fmt.Fprintf(os.Stderr, "failed to remove tmp directory: %v\n", removeErr)
+// StopAll stops every running node, e.g. on Ctrl-C so that no bitcoind is left behind
+func StopAll() {
+	running.Lock()
+	nodes := make([]*node, 0, len(running.nodes))
+	for n := range running.nodes {
+		nodes = append(nodes, n)
+	}
+	running.Unlock()
+
+	var wg sync.WaitGroup
+	for _, n := range nodes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n.close()
+		}()
+	}
+	wg.Wait()
+}
+
+// bindFailureRe matches what bitcoind logs when one of its ports was taken, by another process or node
+var bindFailureRe = regexp.MustCompile(`Unable to bind|Binding RPC on address .* failed|Address already in use`)
+
+var errPortTaken = errors.New("a port of bitcoind was taken")
+
+// startBitcoind starts a regtest node on free ports, publishing the given ZMQ topics, and waits until it answers RPCs.
+// The ports could be taken before bitcoind binds them, so it is started again on fresh ports when that happens.
+func startBitcoind(path string, opts CaptureOptions, zmq []ZmqTopic) (conf Config, err error) {
+	opts = opts.withDefaults()
+	for attempt := 1; ; attempt++ {
+		conf, err = startBitcoindOnce(path, opts, zmq)
+		if !errors.Is(err, errPortTaken) || attempt == startAttempts {
+			return
 		}
+		log.Printf("bitcoind %s couldn't bind its ports, retrying on others", path)
 	}
+}
 
-	cmd := exec.Command(path, "-server", "-regtest", "-daemonwait", "-datadir="+tmpDirectory)
-	if err = cmd.Start(); err != nil {
+func startBitcoindOnce(path string, opts CaptureOptions, zmq []ZmqTopic) (conf Config, err error) {
+	n, err := startNode(path, opts.StopTimeout, zmq)
+	if err != nil {
 		return
 	}
-
 	defer func() {
 		if err != nil {
-			removeTempDir()
+			n.close()
 		}
 	}()
-	err = cmd.Wait()
+
+	networkParams := chaincfg.RegressionNetParams
+	host := "127.0.0.1:" + strconv.Itoa(n.rpcPort)
+	cookiePath := n.dataDir + "/regtest/.cookie"
+	client, err := rpcclient.New(&rpcclient.ConnConfig{
+		Host:         host,
+		Params:       networkParams.Name,
+		DisableTLS:   true,
+		HTTPPostMode: true,
+		CookiePath:   cookiePath,
+	}, nil)
 	if err != nil {
 		return
 	}
 
-	pidPath := tmpDirectory + "/regtest/bitcoind.pid"
-	pidBytes, err := os.ReadFile(pidPath)
+	err = n.waitReady(client, opts.StartupTimeout)
 	if err != nil {
+		client.Shutdown()
+		if n.bindFailed() {
+			err = fmt.Errorf("%w: %w", errPortTaken, err)
+		}
+		err = n.fail(err)
 		return
 	}
-	pid, err := strconv.Atoi(strings.TrimSpace(string(pidBytes)))
+
+	// a hung capture is unblocked by stopping the node, which fails its pending requests
+	var timedOut atomic.Bool
+	captureTimer := time.AfterFunc(opts.CaptureTimeout, func() {
+		timedOut.Store(true)
+		n.stop()
+	})
+	cleanup := func() {
+		captureTimer.Stop()
+		client.Shutdown()
+		n.close()
+	}
+	fail := func(err error) error {
+		if timedOut.Load() {
+			err = fmt.Errorf("capture timed out after %v: %w", opts.CaptureTimeout, err)
+		}
+		return n.fail(err)
+	}
+	conf = Config{Client: client, Cleanup: cleanup, Fail: fail}
+	return
+}
+
+func startNode(path string, stopWait time.Duration, zmq []ZmqTopic) (*node, error) {
+	tmpDirectory, err := os.MkdirTemp("", "bitcoinrpcschema-bitcoind")
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	p, err := os.FindProcess(pid)
+	ports, err := freePorts(2 + len(zmq))
 	if err != nil {
-		return
+		removeDir(tmpDirectory)
+		return nil, err
 	}
+	rpcPort, p2pPort := ports[0], ports[1]
 
-	cleanup := func() {
-		err := p.Signal(syscall.SIGTERM)
+	args := []string{"-server", "-regtest", "-datadir=" + tmpDirectory,
+		"-rpcport=" + strconv.Itoa(rpcPort), "-port=" + strconv.Itoa(p2pPort),
+		"-bind=127.0.0.1:" + strconv.Itoa(p2pPort), "-listenonion=0"}
+	cmd := exec.Command(path, append(args, zmqArgs(zmq, ports[2:])...)...)
+	// own process group, so that a Ctrl-C in the terminal doesn't reach bitcoind before we stop it
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		removeDir(tmpDirectory)
+		return nil, err
+	}
+
+	n := &node{
+		cmd:      cmd,
+		dataDir:  tmpDirectory,
+		rpcPort:  rpcPort,
+		exited:   make(chan struct{}),
+		stopWait: stopWait,
+	}
+	go func() {
+		_ = cmd.Wait()
+		close(n.exited)
+	}()
+
+	running.Lock()
+	running.nodes[n] = struct{}{}
+	running.Unlock()
+	return n, nil
+}
+
+// waitReady polls the node until it answers RPCs, it exits or the timeout expires
+func (n *node) waitReady(client *rpcclient.Client, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(probeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-n.exited:
+			return fmt.Errorf("bitcoind exited during startup: %v", n.cmd.ProcessState)
+		case <-ctx.Done():
+			return fmt.Errorf("bitcoind not ready after %v", timeout)
+		case <-ticker.C:
+		}
+
+		// the client retries refused connections with backoff, so only call it once the port is open
+		conn, err := net.DialTimeout("tcp", "127.0.0.1:"+strconv.Itoa(n.rpcPort), probeInterval)
 		if err != nil {
+			continue
+		}
+		_ = conn.Close()
+
+		// while warming up, bitcoind answers with an error, and a node stuck in init might never answer
+		probe := make(chan error, 1)
+		go func() {
+			_, err := client.GetBlockCount()
+			probe <- err
+		}()
+		select {
+		case err = <-probe:
+			if err == nil {
+				return nil
+			}
+		case <-n.exited:
+			return fmt.Errorf("bitcoind exited during startup: %v", n.cmd.ProcessState)
+		case <-ctx.Done():
+			return fmt.Errorf("bitcoind not ready after %v, it accepts connections but doesn't answer RPCs", timeout)
+		}
+	}
+}
+
+// stop sends SIGTERM to the node, escalating to SIGKILL if it hasn't exited in time
+func (n *node) stop() {
+	n.stopOnce.Do(func() {
+		pgid := -n.cmd.Process.Pid
+		err := syscall.Kill(pgid, syscall.SIGTERM)
+		if err != nil && !errors.Is(err, syscall.ESRCH) {
 			_, _ = fmt.Fprintf(os.Stderr, "failed to stop bitcoind: %v\n", err)
-		} else {
-			_, _ = p.Wait()
 		}
-		removeTempDir()
+		select {
+		case <-n.exited:
+		case <-time.After(n.stopWait):
+			_, _ = fmt.Fprintf(os.Stderr, "bitcoind didn't stop after %v, killing it\n", n.stopWait)
+			_ = syscall.Kill(pgid, syscall.SIGKILL)
+			<-n.exited
+		}
+	})
+}
+
+// close stops the node and removes its data directory
+func (n *node) close() {
+	n.closeOnce.Do(func() {
+		n.stop()
+		removeDir(n.dataDir)
+
+		running.Lock()
+		delete(running.nodes, n)
+		running.Unlock()
+	})
+}
+
+// fail attaches the tail of the node's debug.log to err
+func (n *node) fail(err error) error {
+	tail, logErr := tailFile(path.Join(n.dataDir, "regtest", "debug.log"), logTailLines)
+	if logErr != nil {
+		return err
 	}
+	return &NodeError{Err: err, LogTail: tail}
+}
 
-	networkParams := chaincfg.RegressionNetParams
-	host := "localhost:18443" // regtest default
-	cookiePath := tmpDirectory + "/regtest/.cookie"
-	client, err := rpcclient.New(&rpcclient.ConnConfig{
-		Host:         host,
-		Params:       networkParams.Name,
-		DisableTLS:   true,
-		HTTPPostMode: true,
-		CookiePath:   cookiePath,
-	}, nil)
+// bindFailed tells whether the node logged that it couldn't bind one of its ports
+func (n *node) bindFailed() bool {
+	b, err := os.ReadFile(path.Join(n.dataDir, "regtest", "debug.log"))
+	return err == nil && bindFailureRe.Match(b)
+}
+
+func tailFile(p string, lines int) (string, error) {
+	b, err := os.ReadFile(p)
 	if err != nil {
-		cleanup()
-		return
+		return "", err
 	}
-	conf = Config{Client: client, Cleanup: cleanup}
-	return
+	all := strings.Split(strings.TrimRight(string(b), "\n"), "\n")
+	if len(all) > lines {
+		all = all[len(all)-lines:]
+	}
+	return strings.Join(all, "\n"), nil
+}
+
+func removeDir(dir string) {
+	err := os.RemoveAll(dir)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "failed to remove tmp directory: %v\n", err)
+	}
+}
+
+// handedOut are the ports given to nodes, which are skipped so that concurrent workers never share one,
+// even before bitcoind has bound it. A capture starts few enough nodes for them to be kept.
+var handedOut = struct {
+	sync.Mutex
+	ports map[int]bool
+}{ports: make(map[int]bool)}
+
+// freePorts finds n distinct ports that are currently free on localhost, so that several nodes can run at once.
+// Another process could take one before bitcoind binds it, in which case startBitcoind retries.
+func freePorts(n int) ([]int, error) {
+	handedOut.Lock()
+	defer handedOut.Unlock()
+	ports := make([]int, 0, n)
+	// the listeners are kept open until every port is picked, so that they differ
+	for len(ports) < n {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return nil, fmt.Errorf("error finding free port: %w", err)
+		}
+		defer func() {
+			_ = l.Close()
+		}()
+		port := l.Addr().(*net.TCPAddr).Port
+		if !handedOut.ports[port] {
+			ports = append(ports, port)
+		}
+	}
+	for _, port := range ports {
+		handedOut.ports[port] = true
+	}
+	return ports, nil
 }
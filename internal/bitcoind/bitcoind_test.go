@@ -0,0 +1,111 @@
+package bitcoind
+
+import (
+	"errors"
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeBitcoind writes a script that logs to debug.log in its datadir and then runs body
+func fakeBitcoind(t *testing.T, body string) string {
+	script := `#!/bin/sh
+for arg in "$@"; do
+	case "$arg" in -datadir=*) datadir="${arg#-datadir=}" ;; esac
+done
+mkdir -p "$datadir/regtest"
+echo "Bitcoin Core starting" >> "$datadir/regtest/debug.log"
+echo "Error: something went wrong" >> "$datadir/regtest/debug.log"
+` + body + "\n"
+	p := path.Join(t.TempDir(), "bitcoind")
+	require.NoError(t, os.WriteFile(p, []byte(script), 0755))
+	return p
+}
+
+func TestStartupFailureHasLogTail(t *testing.T) {
+	_, err := startBitcoind(fakeBitcoind(t, "exit 1"), CaptureOptions{StartupTimeout: 10 * time.Second}, nil)
+	require.Error(t, err)
+	var nodeErr *NodeError
+	require.True(t, errors.As(err, &nodeErr))
+	assert.Contains(t, nodeErr.LogTail, "Error: something went wrong")
+	assert.Contains(t, err.Error(), "exited during startup")
+}
+
+func TestStartupRetriesBindFailure(t *testing.T) {
+	attempts := path.Join(t.TempDir(), "attempts")
+	bitcoind := fakeBitcoind(t, `echo started >> `+attempts+`
+echo "Error: Unable to bind to 127.0.0.1:18444 on this computer." >> "$datadir/regtest/debug.log"
+exit 1`)
+	_, err := startBitcoind(bitcoind, CaptureOptions{StartupTimeout: 10 * time.Second}, nil)
+	require.Error(t, err)
+	b, err := os.ReadFile(attempts)
+	require.NoError(t, err)
+	assert.Equal(t, startAttempts, strings.Count(string(b), "started"))
+}
+
+func TestStartupTimeoutStopsNode(t *testing.T) {
+	start := time.Now()
+	_, err := startBitcoind(fakeBitcoind(t, "trap '' TERM\nwhile true; do sleep 1; done"),
+		CaptureOptions{StartupTimeout: 300 * time.Millisecond, StopTimeout: 300 * time.Millisecond}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not ready")
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	running.Lock()
+	defer running.Unlock()
+	assert.Empty(t, running.nodes)
+}
+
+func TestFreePortsDiffer(t *testing.T) {
+	ports, err := freePorts(5)
+	require.NoError(t, err)
+	more, err := freePorts(5)
+	require.NoError(t, err)
+	ports = append(ports, more...)
+	for i, p := range ports {
+		assert.NotContains(t, ports[i+1:], p)
+	}
+}
+
+func TestWaitReadyTimesOutUnansweredRpc(t *testing.T) {
+	// accepts connections, but never answers
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() {
+		_ = l.Close()
+	}()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() {
+				_ = conn.Close()
+			})
+		}
+	}()
+	port := l.Addr().(*net.TCPAddr).Port
+	client, err := rpcclient.New(&rpcclient.ConnConfig{
+		Host:         "127.0.0.1:" + strconv.Itoa(port),
+		User:         "user",
+		Pass:         "pass",
+		DisableTLS:   true,
+		HTTPPostMode: true,
+	}, nil)
+	require.NoError(t, err)
+	defer client.Shutdown()
+
+	n := &node{rpcPort: port, exited: make(chan struct{})}
+	start := time.Now()
+	err = n.waitReady(client, 300*time.Millisecond)
+	assert.ErrorContains(t, err, "doesn't answer RPCs")
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
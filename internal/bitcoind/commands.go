@@ -1,57 +1,48 @@
 package bitcoind
 
 import (
+	"bitcoinrpcschema/internal/version"
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/btcsuite/btcd/rpcclient"
+	"log"
 	"regexp"
-	"strconv"
 	"strings"
 )
 
 type Command struct {
-	Name string
-	Help string
+	Name           string     `json:"name"`
+	Help           string     `json:"help"`
+	Arguments      []Argument `json:"arguments,omitempty"`
+	Results        []Result   `json:"results,omitempty"`
+	RequiresWallet bool       `json:"requiresWallet,omitempty"`
 }
 
-var versionRe = regexp.MustCompile(`(\d+)\.(\d+)\.(\d+)`)
-
-func getVersion(c *rpcclient.Client) (rv ReleaseVersion, err error) {
-	i, err := c.GetNetworkInfo()
+// parseHelp fills in the structured fields of the command from its help text
+func (c *Command) parseHelp() error {
+	var errs []error
+	args, err := ParseArguments(c.Help)
 	if err != nil {
-		return
-	}
-	matches := versionRe.FindStringSubmatch(i.SubVersion)
-	if len(matches) < 4 {
-		err = fmt.Errorf("could not parse version %s: got regex matches %v", i.SubVersion, matches)
-		return
+		errs = append(errs, fmt.Errorf("failed to parse arguments of %s: %w", c.Name, err))
 	}
+	c.Arguments = args
 
-	rv.Major, err = atou(matches[1])
-	if err != nil {
-		err = fmt.Errorf("could not parse major version from %s: %w", matches[1], err)
-		return
-	}
-	rv.Minor, err = atou(matches[2])
+	results, err := ParseResults(c.Help)
 	if err != nil {
-		err = fmt.Errorf("could not parse minor version from %s: %w", matches[2], err)
-		return
+		errs = append(errs, fmt.Errorf("failed to parse results of %s: %w", c.Name, err))
 	}
-	rv.Patch, err = atou(matches[3])
-	if err != nil {
-		err = fmt.Errorf("could not parse patch version from %s: %w", matches[3], err)
-		return
-	}
-	return
+	c.Results = results
+	return errors.Join(errs...)
 }
 
-func atou(s string) (uint, error) {
-	i, err := strconv.Atoi(s)
+func getVersion(c *rpcclient.Client) (version.Version, error) {
+	i, err := c.GetNetworkInfo()
 	if err != nil {
-		return 0, err
+		return version.Version{}, err
 	}
-	return uint(i), nil
+	return version.ParseSubversion(i.SubVersion)
 }
 
 func getCommandHelps(c *rpcclient.Client, hiddenCommands []string) (map[string][]Command, error) {
@@ -68,7 +59,11 @@ func getCommandHelps(c *rpcclient.Client, hiddenCommands []string) (map[string][
 			if err != nil {
 				return nil, err
 			}
-			helps[section] = append(helps[section], Command{Name: command, Help: help})
+			cmd := Command{Name: command, Help: help}
+			if err := cmd.parseHelp(); err != nil {
+				log.Printf("error parsing help: %v", err)
+			}
+			helps[section] = append(helps[section], cmd)
 		}
 	}
 	return helps, nil
@@ -1,123 +1,360 @@
 package bitcoind
 
 import (
-	"bytes"
-	"encoding/gob"
+	"bitcoinrpcschema/internal/version"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"github.com/panjf2000/ants/v2"
+	"io"
 	"log"
 	"os"
 	"path"
+	"strings"
+	"sync"
+	"time"
 )
 
-type RpcDb map[ReleaseVersion]map[string][]Command
+// RpcDb holds the commands of each release, by section
+type RpcDb map[version.Version]map[string][]Command
 
-// this is also defined in downloader, but the two needn't be identical
-type ReleaseVersion struct {
-	Major uint
-	Minor uint
-	Patch uint
+// Db is everything captured about each release
+type Db struct {
+	Releases map[version.Version]*Release
 }
 
-func (v ReleaseVersion) String() string {
-	if v.Patch != 0 {
-		return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+// Release is what was captured from a single bitcoind release
+type Release struct {
+	Metadata Metadata             `json:"metadata"`
+	Commands map[string][]Command `json:"commands"`
+	Options  []OptionGroup        `json:"options,omitempty"`
+	Tools    map[string]ToolHelp  `json:"tools,omitempty"`
+	Rest     []RestEndpoint       `json:"rest,omitempty"`
+	Zmq      []ZmqTopic           `json:"zmq,omitempty"`
+}
+
+// Metadata records when, where and from which binary a release was captured
+type Metadata struct {
+	CapturedAt     time.Time `json:"capturedAt"`
+	Host           string    `json:"host,omitempty"`
+	BitcoindPath   string    `json:"bitcoindPath,omitempty"`
+	BitcoindSha256 string    `json:"bitcoindSha256,omitempty"`
+}
+
+// NewDb makes a database of commands without any other capture data
+func NewDb(rpcs RpcDb) *Db {
+	db := &Db{Releases: make(map[version.Version]*Release, len(rpcs))}
+	for v, cmds := range rpcs {
+		db.Releases[v] = &Release{Commands: cmds}
 	}
-	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+	return db
 }
 
-func (v ReleaseVersion) Cmp(other ReleaseVersion) int {
-	if v.Major != other.Major {
-		return int(v.Major - other.Major)
+// Rpcs returns the commands of every release
+func (db *Db) Rpcs() RpcDb {
+	rpcs := make(RpcDb, len(db.Releases))
+	for v, release := range db.Releases {
+		rpcs[v] = release.Commands
 	}
-	if v.Minor != other.Minor {
-		return int(v.Minor - other.Minor)
+	return rpcs
+}
+
+// readLabel returns the label of a build from a git ref, or "" for a release
+func readLabel(versionPath string) (string, error) {
+	b, err := os.ReadFile(path.Join(versionPath, version.LabelFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
 	}
-	return int(v.Patch - other.Patch)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
 }
 
-func CreateDb(daemonPath string) ([]byte, error) {
-	rpcDb, err := mkDb(daemonPath)
+// CaptureOptions control how releases are captured. Zero timeouts use defaults.
+type CaptureOptions struct {
+	// Workers is the number of bitcoind instances run at once
+	Workers int
+	// StartupTimeout is how long bitcoind has to start answering RPCs
+	StartupTimeout time.Duration
+	// StopTimeout is how long bitcoind has to exit after SIGTERM before it is killed
+	StopTimeout time.Duration
+	// CaptureTimeout is how long capturing a running bitcoind may take
+	CaptureTimeout time.Duration
+}
+
+func (o CaptureOptions) withDefaults() CaptureOptions {
+	if o.StartupTimeout == 0 {
+		o.StartupTimeout = defaultStartupTimeout
+	}
+	if o.StopTimeout == 0 {
+		o.StopTimeout = defaultStopTimeout
+	}
+	if o.CaptureTimeout == 0 {
+		o.CaptureTimeout = defaultCaptureTimeout
+	}
+	return o
+}
+
+func CreateDb(daemonPath string, opts CaptureOptions) ([]byte, error) {
+	db := &Db{Releases: make(map[version.Version]*Release)}
+	err := mkDb(daemonPath, db, opts)
 	if err != nil {
 		e := fmt.Errorf("error getting commands for daemon %s: %v", daemonPath, err)
 		return nil, e
 	}
-	return rpcDb.Marshal()
+	return db.Marshal()
 }
 
-func (db RpcDb) Marshal() ([]byte, error) {
-	var b bytes.Buffer
-	enc := gob.NewEncoder(&b)
-	err := enc.Encode(db)
+// UpdateDb captures only the releases whose bitcoind binary isn't already in the existing DB.
+// A release recaptured from a different binary replaces the existing one, and releases that are
+// no longer on disk are kept.
+func UpdateDb(daemonPath string, existing []byte, opts CaptureOptions) ([]byte, error) {
+	db, err := ReadDb(existing)
 	if err != nil {
-		e := fmt.Errorf("error encoding commands: %v", err)
+		e := fmt.Errorf("error reading existing db: %w", err)
 		return nil, e
 	}
-	return b.Bytes(), nil
-}
-
-func ReadDb(db []byte) (RpcDb, error) {
-	var cmds RpcDb
-	dec := gob.NewDecoder(bytes.NewReader(db))
-	err := dec.Decode(&cmds)
+	err = mkDb(daemonPath, db, opts)
 	if err != nil {
-		e := fmt.Errorf("error decoding commands: %v", err)
+		e := fmt.Errorf("error getting commands for daemon %s: %v", daemonPath, err)
 		return nil, e
 	}
-	return cmds, nil
+	return db.Marshal()
+}
+
+// Marshal encodes the commands as a database without capture metadata
+func (db RpcDb) Marshal() ([]byte, error) {
+	return NewDb(db).Marshal()
+}
+
+// parseHelps fills in structured help for commands captured before it was parsed
+func (db *Db) parseHelps() {
+	for _, release := range db.Releases {
+		for _, cmds := range release.Commands {
+			for i := range cmds {
+				if cmds[i].Arguments != nil || cmds[i].Results != nil {
+					continue
+				}
+				if err := cmds[i].parseHelp(); err != nil {
+					log.Printf("error parsing help: %v", err)
+				}
+			}
+		}
+	}
 }
 
-func mkDb(daemonPath string) (RpcDb, error) {
+// mkDb captures every release in daemonPath into db, except those captured from the same bitcoind binary.
+// Releases that fail to capture are logged and skipped.
+func mkDb(daemonPath string, db *Db, opts CaptureOptions) error {
 	dirs, err := os.ReadDir(daemonPath)
 	if err != nil {
 		e := fmt.Errorf("error reading directory: %v", err)
-		return nil, e
+		return e
 	}
 
-	db := make(RpcDb, len(dirs))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	p, err := ants.NewPoolWithFunc(max(opts.Workers, 1), func(i interface{}) {
+		defer wg.Done()
+		entryPath := i.(string)
+		rv, release, err := getRpcInfo(entryPath, opts)
+		if err != nil {
+			log.Printf("error getting commands: %v", err)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		db.Releases[rv] = release
+	})
+	if err != nil {
+		return fmt.Errorf("error creating capture pool: %w", err)
+	}
+	defer p.Release()
+
+	captured := db.capturedBinaries()
 	for _, dir := range dirs {
 		entryPath := path.Join(daemonPath, dir.Name())
-		version, cmds, err := getRpcInfo(entryPath)
+		sum, err := fileSha256(path.Join(entryPath, "bin", "bitcoind"))
 		if err != nil {
-			log.Printf("error getting commands: %v", err)
+			log.Printf("error hashing bitcoind: %v", err)
+			continue
+		}
+		if v, ok := captured[sum]; ok {
+			log.Printf("skipping %s, already captured as %s", entryPath, v)
+			continue
+		}
+		wg.Add(1)
+		err = p.Invoke(entryPath)
+		if err != nil {
+			wg.Done()
+			wg.Wait()
+			return fmt.Errorf("error invoking capture pool: %w", err)
 		}
-		db[version] = cmds
 	}
-	return db, nil
+	wg.Wait()
+	return nil
 }
 
-func getRpcInfo(versionPath string) (ReleaseVersion, map[string][]Command, error) {
+// capturedBinaries maps the hash of each captured bitcoind to its release
+func (db *Db) capturedBinaries() map[string]version.Version {
+	captured := make(map[string]version.Version, len(db.Releases))
+	for v, release := range db.Releases {
+		if release.Metadata.BitcoindSha256 != "" {
+			captured[release.Metadata.BitcoindSha256] = v
+		}
+	}
+	return captured
+}
+
+func getRpcInfo(versionPath string, opts CaptureOptions) (version.Version, *Release, error) {
 	hiddenCommands, err := getHiddenCommands(versionPath)
 	if err != nil {
 		e := fmt.Errorf("error getting hidden commands for bitcoind %s: %w", versionPath, err)
-		return ReleaseVersion{}, nil, e
+		return version.Version{}, nil, e
 	}
 
 	bitcoindPath := path.Join(versionPath, "bin", "bitcoind")
-	rv, cmds, err := GetDaemonCommands(bitcoindPath, hiddenCommands)
+	metadata, err := getMetadata(bitcoindPath)
+	if err != nil {
+		e := fmt.Errorf("error getting metadata for bitcoind %s: %w", bitcoindPath, err)
+		return version.Version{}, nil, e
+	}
+	options, err := GetDaemonOptions(bitcoindPath, opts.withDefaults().StartupTimeout)
+	if err != nil {
+		e := fmt.Errorf("error getting options for bitcoind %s: %w", bitcoindPath, err)
+		return version.Version{}, nil, e
+	}
+	zmq := zmqTopics(options)
+	info, err := getDaemonInfo(bitcoindPath, hiddenCommands, zmq, opts)
 	if err != nil {
 		err = fmt.Errorf("error getting RPC info for bitcoind %s: %w", bitcoindPath, err)
+		return version.Version{}, nil, err
 	}
-	return rv, cmds, err
+	out, err := runHelp(bitcoindPath, opts.withDefaults().StartupTimeout, "-version")
+	if err != nil {
+		e := fmt.Errorf("error getting version of bitcoind %s: %w", bitcoindPath, err)
+		return version.Version{}, nil, e
+	}
+	full, err := version.ParseFull(out)
+	if err != nil {
+		e := fmt.Errorf("error parsing version of bitcoind %s: %w", bitcoindPath, err)
+		return version.Version{}, nil, e
+	}
+	if full.Major != info.version.Major || full.Minor != info.version.Minor || full.Patch != info.version.Patch {
+		log.Printf("bitcoind %s reports version %s, but %s over RPC", bitcoindPath, full, info.version)
+	}
+	info.version.Pre, info.version.Suffix = full.Pre, full.Suffix
+	info.version.Label, err = readLabel(versionPath)
+	if err != nil {
+		e := fmt.Errorf("error reading label of %s: %w", versionPath, err)
+		return version.Version{}, nil, e
+	}
+	err = markWalletCommands(versionPath, info.commands)
+	if err != nil {
+		e := fmt.Errorf("error getting wallet commands for bitcoind %s: %w", versionPath, err)
+		return version.Version{}, nil, e
+	}
+	setZmqNotifications(zmq, info.zmqNotifications)
+	setZmqBodies(zmq, info.version)
+	err = addZmqSource(versionPath, zmq)
+	if err != nil {
+		e := fmt.Errorf("error getting ZMQ topics for %s: %w", versionPath, err)
+		return version.Version{}, nil, e
+	}
+	tools, err := GetToolHelps(path.Dir(bitcoindPath), opts.withDefaults().StartupTimeout)
+	if err != nil {
+		e := fmt.Errorf("error getting tool help for %s: %w", versionPath, err)
+		return version.Version{}, nil, e
+	}
+	rest, err := getRestEndpoints(versionPath)
+	if err != nil {
+		e := fmt.Errorf("error getting REST endpoints for %s: %w", versionPath, err)
+		return version.Version{}, nil, e
+	}
+	return info.version, &Release{
+		Metadata: metadata,
+		Commands: info.commands,
+		Options:  options,
+		Tools:    tools,
+		Rest:     rest,
+		Zmq:      zmq,
+	}, nil
 }
 
-func GetDaemonCommands(bitcoindPath string, hiddenCommands []string) (ReleaseVersion, map[string][]Command, error) {
-	conf, err := startBitcoind(bitcoindPath)
+func getMetadata(bitcoindPath string) (Metadata, error) {
+	sum, err := fileSha256(bitcoindPath)
 	if err != nil {
-		return ReleaseVersion{}, nil, err
+		return Metadata{}, err
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		return Metadata{}, err
+	}
+	return Metadata{
+		CapturedAt:     time.Now().UTC(),
+		Host:           host,
+		BitcoindPath:   bitcoindPath,
+		BitcoindSha256: sum,
+	}, nil
+}
+
+func fileSha256(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// daemonInfo is what is captured from a running node
+type daemonInfo struct {
+	version          version.Version
+	commands         map[string][]Command
+	zmqNotifications []zmqNotification
+}
+
+// getDaemonInfo captures a node started with loaded wallets and publishing the given ZMQ topics
+func getDaemonInfo(bitcoindPath string, hiddenCommands []string, zmq []ZmqTopic, opts CaptureOptions) (*daemonInfo, error) {
+	conf, err := startBitcoind(bitcoindPath, opts, zmq)
+	if err != nil {
+		return nil, err
 	}
 	defer conf.Cleanup()
 	c := conf.Client
 
 	v, err := getVersion(c)
 	if err != nil {
-		e := fmt.Errorf("error getting version for bitcoind %s: %w", bitcoindPath, err)
-		return ReleaseVersion{}, nil, e
+		e := fmt.Errorf("error getting version for bitcoind %s: %w", bitcoindPath, conf.Fail(err))
+		return nil, e
+	}
+
+	err = createWallets(c, v)
+	if err != nil {
+		e := fmt.Errorf("error creating wallets for bitcoind %s: %w", bitcoindPath, conf.Fail(err))
+		return nil, e
 	}
 
 	cmds, err := getCommandHelps(c, hiddenCommands)
 	if err != nil {
-		e := fmt.Errorf("error getting commands for bitcoind %s: %w", bitcoindPath, err)
-		return ReleaseVersion{}, nil, e
+		e := fmt.Errorf("error getting commands for bitcoind %s: %w", bitcoindPath, conf.Fail(err))
+		return nil, e
+	}
+
+	var notifications []zmqNotification
+	if len(zmq) > 0 {
+		notifications, err = getZmqNotifications(c)
+		if err != nil {
+			log.Printf("error getting ZMQ notifications for bitcoind %s: %v", bitcoindPath, err)
+		}
 	}
-	return v, cmds, nil
+	return &daemonInfo{version: v, commands: cmds, zmqNotifications: notifications}, nil
 }
@@ -0,0 +1,102 @@
+package bitcoind
+
+import (
+	"bitcoinrpcschema/internal/version"
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// The database is stored as indented JSON so that snapshots can be committed and diffed in review.
+// A header identifies the format and its version. Version 1 was a bare gob encoding of an RpcDb,
+// which ReadDb still accepts and migrates.
+
+const dbFormat = "bitcoinrpcdev-db"
+
+// DbFormatVersion is the version of the on-disk database format written by Marshal
+const DbFormatVersion = 2
+
+type dbFile struct {
+	Format        string        `json:"format"`
+	FormatVersion int           `json:"formatVersion"`
+	Releases      []releaseFile `json:"releases"`
+}
+
+type releaseFile struct {
+	Version version.Version `json:"version"`
+	Name    string          `json:"name"`
+	Release
+}
+
+// Marshal encodes the database in the current on-disk format, with releases in ascending version order
+func (db *Db) Marshal() ([]byte, error) {
+	f := dbFile{
+		Format:        dbFormat,
+		FormatVersion: DbFormatVersion,
+		Releases:      make([]releaseFile, 0, len(db.Releases)),
+	}
+	for v, release := range db.Releases {
+		f.Releases = append(f.Releases, releaseFile{Version: v, Name: v.String(), Release: *release})
+	}
+	slices.SortFunc(f.Releases, func(a, b releaseFile) int {
+		return a.Version.Cmp(b.Version)
+	})
+
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		e := fmt.Errorf("error encoding database: %v", err)
+		return nil, e
+	}
+	return b, nil
+}
+
+// ReadDb decodes a database in the current format, or migrates one in an older format
+func ReadDb(b []byte) (*Db, error) {
+	var db *Db
+	var err error
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("{")) {
+		db, err = readJsonDb(b)
+	} else {
+		db, err = readGobDb(b)
+	}
+	if err != nil {
+		return nil, err
+	}
+	db.parseHelps()
+	return db, nil
+}
+
+func readJsonDb(b []byte) (*Db, error) {
+	var f dbFile
+	err := json.Unmarshal(b, &f)
+	if err != nil {
+		e := fmt.Errorf("error decoding database: %v", err)
+		return nil, e
+	}
+	if f.Format != dbFormat {
+		return nil, fmt.Errorf("unrecognized database format %q", f.Format)
+	}
+	if f.FormatVersion != DbFormatVersion {
+		return nil, fmt.Errorf("unsupported database format version %d, expected %d", f.FormatVersion, DbFormatVersion)
+	}
+
+	db := &Db{Releases: make(map[version.Version]*Release, len(f.Releases))}
+	for _, r := range f.Releases {
+		db.Releases[r.Version] = &r.Release
+	}
+	return db, nil
+}
+
+// readGobDb reads a version 1 database
+func readGobDb(b []byte) (*Db, error) {
+	var cmds RpcDb
+	dec := gob.NewDecoder(bytes.NewReader(b))
+	err := dec.Decode(&cmds)
+	if err != nil {
+		e := fmt.Errorf("error decoding commands: %v", err)
+		return nil, e
+	}
+	return NewDb(cmds), nil
+}
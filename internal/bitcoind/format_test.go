@@ -0,0 +1,110 @@
+package bitcoind
+
+import (
+	"bitcoinrpcschema/internal/version"
+	"bytes"
+	"encoding/gob"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestDbRoundTrip(t *testing.T) {
+	v := version.Version{Major: 27, Minor: 1}
+	db := &Db{Releases: map[version.Version]*Release{
+		v: {
+			Metadata: Metadata{
+				CapturedAt:     time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC),
+				Host:           "host",
+				BitcoindPath:   "/bitcoin-27.1/bin/bitcoind",
+				BitcoindSha256: "abcd",
+			},
+			Commands: map[string][]Command{
+				"Blockchain": {{Name: "getblockcount", Help: "getblockcount\n", Results: []Result{{Value: ResultField{Type: "numeric"}}}}},
+			},
+		},
+		{Major: 26}: {Commands: map[string][]Command{}},
+		{Major: 27, Minor: 99, Label: "master-1a2b3c4"}: {Commands: map[string][]Command{}},
+	}}
+	b, err := db.Marshal()
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"format": "bitcoinrpcdev-db"`)
+	assert.Less(t, bytes.Index(b, []byte(`"name": "26.0"`)), bytes.Index(b, []byte(`"name": "27.1"`)))
+	assert.Less(t, bytes.Index(b, []byte(`"name": "27.1"`)), bytes.Index(b, []byte(`"name": "master-1a2b3c4"`)))
+
+	read, err := ReadDb(b)
+	require.NoError(t, err)
+	assert.Equal(t, db, read)
+}
+
+func TestReadLabel(t *testing.T) {
+	dir := t.TempDir()
+	label, err := readLabel(dir)
+	require.NoError(t, err)
+	assert.Empty(t, label)
+
+	require.NoError(t, os.WriteFile(path.Join(dir, version.LabelFile), []byte("master-1a2b3c4\n"), 0644))
+	label, err = readLabel(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "master-1a2b3c4", label)
+}
+
+func TestReadGobDb(t *testing.T) {
+	// the types of the version 1 format, as rpc.db files were written with them
+	type ReleaseVersion struct {
+		Major uint
+		Minor uint
+		Patch uint
+	}
+	type Command struct {
+		Name string
+		Help string
+	}
+	type RpcDb map[ReleaseVersion]map[string][]Command
+
+	legacy := RpcDb{{Major: 1, Minor: 2, Patch: 3}: {"section": {{Name: "cmd", Help: "cmd\n\nResult:\nn    (numeric) A number\n"}}}}
+	var buf bytes.Buffer
+	require.NoError(t, gob.NewEncoder(&buf).Encode(legacy))
+
+	v := version.Version{Major: 1, Minor: 2, Patch: 3}
+
+	db, err := ReadDb(buf.Bytes())
+	require.NoError(t, err)
+	require.Contains(t, db.Releases, v)
+	assert.True(t, db.Releases[v].Metadata.CapturedAt.IsZero())
+	require.Len(t, db.Releases[v].Commands["section"], 1)
+	cmd := db.Releases[v].Commands["section"][0]
+	assert.Equal(t, "cmd", cmd.Name)
+	require.Len(t, cmd.Results, 1)
+	assert.Equal(t, "numeric", cmd.Results[0].Value.Type)
+}
+
+func TestReadDbRejectsNewerFormat(t *testing.T) {
+	_, err := ReadDb([]byte(`{"format": "bitcoinrpcdev-db", "formatVersion": 99, "releases": []}`))
+	assert.Error(t, err)
+}
+
+func TestUpdateDbSkipsCapturedBinaries(t *testing.T) {
+	daemonPath := t.TempDir()
+	for _, dir := range []string{"bitcoin-1.2.3", "bitcoin-2.3.4"} {
+		binPath := path.Join(daemonPath, dir, "bin")
+		require.NoError(t, os.MkdirAll(binPath, 0755))
+		require.NoError(t, os.WriteFile(path.Join(binPath, "bitcoind"), []byte(dir), 0755))
+	}
+	sum, err := fileSha256(path.Join(daemonPath, "bitcoin-1.2.3", "bin", "bitcoind"))
+	require.NoError(t, err)
+
+	v := version.Version{Major: 1, Minor: 2, Patch: 3}
+	existing, err := (&Db{Releases: map[version.Version]*Release{
+		v: {Metadata: Metadata{BitcoindSha256: sum}, Commands: map[string][]Command{}},
+	}}).Marshal()
+	require.NoError(t, err)
+
+	// 2.3.4 isn't captured yet, but fails to capture without its sources and is skipped
+	updated, err := UpdateDb(daemonPath, existing, CaptureOptions{Workers: 2})
+	require.NoError(t, err)
+	assert.Equal(t, existing, updated)
+}
@@ -0,0 +1,137 @@
+package bitcoind
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// helpSection is a titled block of an RPC help text, such as "Arguments:" or "Result (for verbosity = 1):"
+type helpSection struct {
+	title     string
+	condition string
+	lines     []string
+}
+
+var helpSectionRe = regexp.MustCompile(`^(Arguments|Named Arguments|Result|Examples)(?: \((.+)\))?:$`)
+
+// splitHelp returns the titled sections of a help text. Each section runs until the next blank line.
+func splitHelp(help string) ([]helpSection, error) {
+	var sections []helpSection
+	var cur *helpSection
+	s := bufio.NewScanner(strings.NewReader(help))
+	for s.Scan() {
+		line := s.Text()
+		if matches := helpSectionRe.FindStringSubmatch(line); matches != nil {
+			sections = append(sections, helpSection{title: matches[1], condition: matches[2]})
+			cur = &sections[len(sections)-1]
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			cur = nil
+			continue
+		}
+		cur.lines = append(cur.lines, line)
+	}
+	return sections, s.Err()
+}
+
+// helpEntry is a single row of a help section. Bitcoin Core lays these out as a left column holding the
+// name or JSON structure, and a right column holding "(type, ...) description".
+type helpEntry struct {
+	indent      int
+	left        string
+	meta        string
+	hasMeta     bool
+	description string
+}
+
+// parseHelpEntries splits section lines into entries, folding continuation lines into the preceding entry's
+// description. The right column starts at the first "(" of the first line, and continuation lines are
+// indented up to that column.
+func parseHelpEntries(lines []string) ([]helpEntry, error) {
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	pad := strings.Index(lines[0], " (")
+	if pad < 0 {
+		return nil, fmt.Errorf("could not find description column in %q", lines[0])
+	}
+	pad += len(" ")
+
+	var entries []helpEntry
+	for _, line := range lines {
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if indent >= pad && len(entries) > 0 {
+			prev := &entries[len(entries)-1]
+			prev.description = strings.TrimPrefix(prev.description+"\n"+strings.TrimSpace(line), "\n")
+			continue
+		}
+
+		left, right := line, ""
+		if len(line) > pad && line[pad-1] == ' ' {
+			left, right = line[:pad], line[pad:]
+		} else if i := strings.Index(line, "  "); i > indent {
+			left, right = line[:i], line[i:]
+		}
+		e := helpEntry{indent: indent, left: strings.TrimSpace(left)}
+		right = strings.TrimSpace(right)
+		if strings.HasPrefix(right, "(") {
+			end := closingParen(right)
+			if end < 0 {
+				return nil, fmt.Errorf("unbalanced parentheses in %q", line)
+			}
+			e.meta = right[1:end]
+			e.hasMeta = true
+			right = strings.TrimSpace(right[end+1:])
+		}
+		e.description = right
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// closingParen returns the index of the parenthesis closing the one at the start of s, or -1
+func closingParen(s string) int {
+	depth := 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+var jsonKeyRe = regexp.MustCompile(`^"([^"]*)"\s?:\s*`)
+
+// splitJsonKey splits a left column like `"txid": "hex",` into its key and the remainder
+func splitJsonKey(left string) (string, string) {
+	matches := jsonKeyRe.FindStringSubmatch(left)
+	if matches == nil {
+		return "", left
+	}
+	return matches[1], left[len(matches[0]):]
+}
+
+func opensContainer(left string) bool {
+	return strings.HasSuffix(left, "{") || strings.HasSuffix(left, "[")
+}
+
+func closesContainer(left string) bool {
+	left = strings.TrimSuffix(left, ",")
+	return left == "}" || left == "]"
+}
+
+func isElision(left string) bool {
+	return strings.TrimSuffix(left, ",") == "..."
+}
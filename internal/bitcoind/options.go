@@ -0,0 +1,226 @@
+package bitcoind
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// OptionGroup is a group of startup options, as listed by -help, e.g. "Connection options"
+type OptionGroup struct {
+	Name    string         `json:"name"`
+	Options []ConfigOption `json:"options"`
+}
+
+// ConfigOption is a startup option, which can also be set in bitcoin.conf
+type ConfigOption struct {
+	Name        string `json:"name"`
+	Value       string `json:"value,omitempty"`
+	Default     string `json:"default,omitempty"`
+	Description string `json:"description"`
+	// Debug options are only listed with -help-debug
+	Debug bool `json:"debug,omitempty"`
+}
+
+// ToolHelp is the -help of a tool shipped with bitcoind, like bitcoin-cli
+type ToolHelp struct {
+	Usage  []string      `json:"usage"`
+	Groups []OptionGroup `json:"groups"`
+	// Commands are what tools like bitcoin-wallet do, given after the options
+	Commands []ToolCommand `json:"commands,omitempty"`
+}
+
+// ToolCommand is a command of a tool, listed under a heading like "Commands:" or "Register Commands:" in its -help
+type ToolCommand struct {
+	// Group is the heading the command is listed under, like Register Commands
+	Group       string `json:"group"`
+	Name        string `json:"name"`
+	Value       string `json:"value,omitempty"`
+	Description string `json:"description"`
+}
+
+// Tools are the binaries besides bitcoind whose help is captured, when a release has them
+var Tools = []string{"bitcoin-cli", "bitcoin-wallet", "bitcoin-tx", "bitcoin-util"}
+
+// GetToolHelps captures the help of every tool in binPath
+func GetToolHelps(binPath string, timeout time.Duration) (map[string]ToolHelp, error) {
+	tools := make(map[string]ToolHelp)
+	for _, name := range Tools {
+		toolPath := path.Join(binPath, name)
+		if _, err := os.Stat(toolPath); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		help, err := runHelp(toolPath, timeout, "-help")
+		if err != nil {
+			return nil, err
+		}
+		tools[name], err = ParseToolHelp(help)
+		if err != nil {
+			e := fmt.Errorf("failed to parse %s -help output: %w", name, err)
+			return nil, e
+		}
+	}
+	return tools, nil
+}
+
+var usageRe = regexp.MustCompile(`^(?:Usage|or):\s+(.+)$`)
+var toolCommandRe = regexp.MustCompile(`^  ([^=\s]+)(?:=(\S+))?$`)
+
+// ParseToolHelp parses the usage lines, option groups and commands of a tool's -help output
+func ParseToolHelp(help string) (ToolHelp, error) {
+	groups, err := ParseOptions(help)
+	if err != nil {
+		return ToolHelp{}, err
+	}
+	tool := ToolHelp{Usage: []string{}, Groups: groups, Commands: parseToolCommands(help)}
+	// usage is either on the "Usage:" and "or:" lines, or indented under a "Usage:" heading
+	underHeading := false
+	for _, line := range strings.Split(help, "\n") {
+		switch m := usageRe.FindStringSubmatch(line); {
+		case m != nil:
+			tool.Usage = append(tool.Usage, m[1])
+		case line == "Usage:":
+			underHeading = true
+		case underHeading && strings.HasPrefix(line, "  "):
+			tool.Usage = append(tool.Usage, strings.TrimSpace(line))
+		default:
+			underHeading = false
+		}
+	}
+	return tool, nil
+}
+
+// parseToolCommands parses the sections of a tool's -help output whose heading ends with "Commands:",
+// laid out like options without a dash
+func parseToolCommands(help string) []ToolCommand {
+	var commands []ToolCommand
+	group := ""
+	for _, line := range strings.Split(help, "\n") {
+		switch m := toolCommandRe.FindStringSubmatch(line); {
+		case optionGroupRe.MatchString(line):
+			group = ""
+			if strings.HasSuffix(line, "Commands:") {
+				group = strings.TrimSuffix(line, ":")
+			}
+		case group == "":
+		case m != nil:
+			commands = append(commands, ToolCommand{Group: group, Name: m[1], Value: m[2]})
+		case len(commands) > 0 && strings.HasPrefix(line, "   "):
+			c := &commands[len(commands)-1]
+			c.Description = strings.TrimSpace(c.Description + " " + strings.TrimSpace(line))
+		}
+	}
+	return commands
+}
+
+// GetDaemonOptions lists the startup options of a bitcoind binary, including debug options
+func GetDaemonOptions(bitcoindPath string, timeout time.Duration) ([]OptionGroup, error) {
+	help, err := runHelp(bitcoindPath, timeout, "-help")
+	if err != nil {
+		return nil, err
+	}
+	debugHelp, err := runHelp(bitcoindPath, timeout, "-help", "-help-debug")
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := ParseOptions(debugHelp)
+	if err != nil {
+		e := fmt.Errorf("failed to parse -help-debug output: %w", err)
+		return nil, e
+	}
+	nonDebug, err := ParseOptions(help)
+	if err != nil {
+		e := fmt.Errorf("failed to parse -help output: %w", err)
+		return nil, e
+	}
+	listed := make(map[string]bool)
+	for _, g := range nonDebug {
+		for _, o := range g.Options {
+			listed[o.Name] = true
+		}
+	}
+	for _, g := range groups {
+		for i := range g.Options {
+			g.Options[i].Debug = !listed[g.Options[i].Name]
+		}
+	}
+	return groups, nil
+}
+
+func runHelp(bitcoindPath string, timeout time.Duration, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, bitcoindPath, args...).Output()
+	if err != nil {
+		e := fmt.Errorf("failed to run %s %s: %w", bitcoindPath, strings.Join(args, " "), err)
+		return "", e
+	}
+	return string(out), nil
+}
+
+var optionGroupRe = regexp.MustCompile(`^(\S.*):$`)
+var optionRe = regexp.MustCompile(`^  -([^=\s]+)(?:=(.+))?$`)
+var optionDefaultRe = regexp.MustCompile(`[(,] ?default: ([^()]*)\)`)
+
+// ParseOptions parses the option groups of bitcoind -help output.
+// Descriptions are wrapped by bitcoind, so their lines are joined with spaces.
+func ParseOptions(help string) ([]OptionGroup, error) {
+	var groups []OptionGroup
+	var option *ConfigOption
+	var description []string
+	endOption := func() {
+		if option == nil {
+			return
+		}
+		option.Description = strings.Join(description, " ")
+		if m := optionDefaultRe.FindStringSubmatch(option.Description); m != nil {
+			option.Default = m[1]
+		}
+		option = nil
+		description = nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(help))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			continue
+		case optionGroupRe.MatchString(line):
+			endOption()
+			groups = append(groups, OptionGroup{Name: optionGroupRe.FindStringSubmatch(line)[1]})
+		case optionRe.MatchString(line):
+			endOption()
+			if len(groups) == 0 {
+				return nil, fmt.Errorf("option %q outside of a group", line)
+			}
+			m := optionRe.FindStringSubmatch(line)
+			g := &groups[len(groups)-1]
+			g.Options = append(g.Options, ConfigOption{Name: m[1], Value: m[2]})
+			option = &g.Options[len(g.Options)-1]
+		case option != nil && strings.HasPrefix(line, "   "):
+			description = append(description, strings.TrimSpace(line))
+		}
+	}
+	endOption()
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// drop headings without options, like "Usage:"
+	nonEmpty := groups[:0]
+	for _, g := range groups {
+		if len(g.Options) > 0 {
+			nonEmpty = append(nonEmpty, g)
+		}
+	}
+	return nonEmpty, nil
+}
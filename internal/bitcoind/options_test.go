@@ -0,0 +1,75 @@
+package bitcoind
+
+import (
+	_ "embed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+//go:embed test/bitcoind-help.txt
+var bitcoindHelp string
+
+//go:embed test/bitcoin-cli-help.txt
+var bitcoinCliHelp string
+
+//go:embed test/bitcoin-wallet-help.txt
+var bitcoinWalletHelp string
+
+func TestParseOptions(t *testing.T) {
+	groups, err := ParseOptions(bitcoindHelp)
+	require.NoError(t, err)
+	require.Len(t, groups, 3)
+	assert.Equal(t, "Options", groups[0].Name)
+	assert.Equal(t, "Connection options", groups[1].Name)
+	assert.Equal(t, "Debugging/Testing options", groups[2].Name)
+
+	require.Len(t, groups[0].Options, 3)
+	assert.Equal(t, ConfigOption{Name: "?", Description: "Print this help message and exit"}, groups[0].Options[0])
+	assert.Equal(t, ConfigOption{
+		Name:        "alertnotify",
+		Value:       "<cmd>",
+		Description: "Execute command when an alert is raised (%s in cmd is replaced by message)",
+	}, groups[0].Options[1])
+	assert.Equal(t, "450", groups[0].Options[2].Default)
+
+	listenOnion := groups[1].Options[1]
+	assert.Equal(t, "listenonion", listenOnion.Name)
+	assert.Empty(t, listenOnion.Value)
+	assert.Equal(t, "1", listenOnion.Default)
+
+	assert.Equal(t, "6, 0 = all", groups[2].Options[0].Default)
+	assert.Equal(t, "-nodebug, supplying <category> is optional", groups[2].Options[1].Default)
+}
+
+func TestParseToolHelp(t *testing.T) {
+	cli, err := ParseToolHelp(bitcoinCliHelp)
+	require.NoError(t, err)
+	require.Len(t, cli.Usage, 4)
+	assert.Equal(t, "bitcoin-cli [options] -named <command> [name=value]...  Send command to Bitcoin Core (with named arguments)", cli.Usage[1])
+	require.Len(t, cli.Groups, 1)
+	assert.Equal(t, ConfigOption{Name: "named", Default: "false", Description: "Pass named instead of positional arguments (default: false)"}, cli.Groups[0].Options[1])
+
+	wallet, err := ParseToolHelp(bitcoinWalletHelp)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bitcoin-wallet [options] <command>"}, wallet.Usage)
+	require.Len(t, wallet.Groups, 1)
+	assert.Equal(t, "Options", wallet.Groups[0].Name)
+	assert.Equal(t, []ToolCommand{
+		{Group: "Commands", Name: "create", Description: "Create new wallet file"},
+		{Group: "Commands", Name: "info", Description: "Get wallet info"},
+	}, wallet.Commands)
+	assert.Empty(t, cli.Commands)
+
+	tx, err := ParseToolHelp("Usage:  bitcoin-tx [options] <hex-tx> [commands]  Update hex-encoded bitcoin transaction\n\n" +
+		"Options:\n\n  -create\n       Create new, empty TX.\n\n" +
+		"Commands:\n\n  delin=N\n       Delete input N from TX\n\n" +
+		"Register Commands:\n\n  load=NAME:FILENAME\n       Load JSON file FILENAME into register NAME\n\n" +
+		"  set=NAME:JSON-STRING\n       Set register NAME to given JSON-STRING\n")
+	require.NoError(t, err)
+	assert.Equal(t, []ToolCommand{
+		{Group: "Commands", Name: "delin", Value: "N", Description: "Delete input N from TX"},
+		{Group: "Register Commands", Name: "load", Value: "NAME:FILENAME", Description: "Load JSON file FILENAME into register NAME"},
+		{Group: "Register Commands", Name: "set", Value: "NAME:JSON-STRING", Description: "Set register NAME to given JSON-STRING"},
+	}, tx.Commands)
+}
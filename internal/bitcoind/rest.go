@@ -0,0 +1,109 @@
+package bitcoind
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// restDir is where the downloader puts the REST sources of a release
+const restDir = "rest"
+
+// RestEndpoint is a path prefix of the REST interface, and the formats it can respond with
+type RestEndpoint struct {
+	Path    string   `json:"path"`
+	Formats []string `json:"formats"`
+}
+
+var restPrefixesRe = regexp.MustCompile(`(?s)uri_prefixes\[\] = \{(.*?)\n\};`)
+var restPrefixRe = regexp.MustCompile(`\{"(/rest/[^"]*)", (\w+)\}`)
+var restHandlerRe = regexp.MustCompile(`(?m)^static bool (rest_\w+)\(`)
+var restCallRe = regexp.MustCompile(`\b(rest_\w+)\(`)
+
+// the response format enum was RetFormat with RF_ values before v24, then RESTResponseFormat
+var restFormatRe = regexp.MustCompile(`(?:RESTResponseFormat::|RF_)(BINARY|HEX|JSON)\b`)
+
+var restFormatNames = map[string]string{"BINARY": "bin", "HEX": "hex", "JSON": "json"}
+
+// ParseRestEndpoints finds the endpoints in the uri_prefixes table of src/rest.cpp.
+// The formats of an endpoint are those handled by its handler and the rest_ functions it calls.
+func ParseRestEndpoints(cpp []byte) ([]RestEndpoint, error) {
+	table := restPrefixesRe.FindSubmatch(cpp)
+	if table == nil {
+		return nil, fmt.Errorf("no uri_prefixes table")
+	}
+	bodies := restHandlerBodies(cpp)
+
+	var endpoints []RestEndpoint
+	for _, m := range restPrefixRe.FindAllSubmatch(table[1], -1) {
+		handler := string(m[2])
+		if _, ok := bodies[handler]; !ok {
+			return nil, fmt.Errorf("no handler %s for %s", handler, m[1])
+		}
+		endpoints = append(endpoints, RestEndpoint{Path: string(m[1]), Formats: restFormats(handler, bodies)})
+	}
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("empty uri_prefixes table")
+	}
+	return endpoints, nil
+}
+
+// restHandlerBodies splits the source into the bodies of its rest_ functions
+func restHandlerBodies(cpp []byte) map[string][]byte {
+	bodies := make(map[string][]byte)
+	matches := restHandlerRe.FindAllSubmatchIndex(cpp, -1)
+	for i, m := range matches {
+		end := len(cpp)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		bodies[string(cpp[m[2]:m[3]])] = cpp[m[1]:end]
+	}
+	return bodies
+}
+
+func restFormats(handler string, bodies map[string][]byte) []string {
+	var formats []string
+	visited := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+		body := bodies[name]
+		for _, m := range restFormatRe.FindAllSubmatch(body, -1) {
+			f := restFormatNames[string(m[1])]
+			if !slices.Contains(formats, f) {
+				formats = append(formats, f)
+			}
+		}
+		for _, m := range restCallRe.FindAllSubmatch(body, -1) {
+			if _, ok := bodies[string(m[1])]; ok {
+				visit(string(m[1]))
+			}
+		}
+	}
+	visit(handler)
+	slices.SortFunc(formats, strings.Compare)
+	return formats
+}
+
+// getRestEndpoints reads the REST endpoints of a release, or nil if its sources weren't downloaded
+func getRestEndpoints(dir string) ([]RestEndpoint, error) {
+	cpp, err := os.ReadFile(path.Join(dir, restDir, "rest.cpp"))
+	if errors.Is(err, os.ErrNotExist) {
+		log.Printf("no REST sources in %s", dir)
+		return nil, nil
+	}
+	if err != nil {
+		e := fmt.Errorf("failed to read file: %w", err)
+		return nil, e
+	}
+	return ParseRestEndpoints(cpp)
+}
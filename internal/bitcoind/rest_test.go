@@ -0,0 +1,48 @@
+package bitcoind
+
+import (
+	_ "embed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+//go:embed test/rest.cpp
+var restCpp []byte
+
+func TestParseRestEndpoints(t *testing.T) {
+	endpoints, err := ParseRestEndpoints(restCpp)
+	require.NoError(t, err)
+	expected := []RestEndpoint{
+		{Path: "/rest/block/notxdetails/", Formats: []string{"bin", "hex", "json"}},
+		{Path: "/rest/block/", Formats: []string{"bin", "hex", "json"}},
+		{Path: "/rest/chaininfo", Formats: []string{"json"}},
+		{Path: "/rest/headers/", Formats: []string{"bin", "hex", "json"}},
+	}
+	assert.Equal(t, expected, endpoints)
+}
+
+func TestParseRestEndpointsOldFormatEnum(t *testing.T) {
+	cpp := []byte(`static bool rest_tx(HTTPRequest* req, const std::string& strURIPart)
+{
+    switch (rf) {
+    case RF_BINARY: {
+        return true;
+    }
+    case RF_JSON: {
+        return true;
+    }
+    }
+}
+
+static const struct {
+    const char* prefix;
+    bool (*handler)(HTTPRequest* req, const std::string& strReq);
+} uri_prefixes[] = {
+      {"/rest/tx/", rest_tx},
+};
+`)
+	endpoints, err := ParseRestEndpoints(cpp)
+	require.NoError(t, err)
+	assert.Equal(t, []RestEndpoint{{Path: "/rest/tx/", Formats: []string{"bin", "json"}}}, endpoints)
+}
@@ -0,0 +1,102 @@
+package bitcoind
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Result is one of the possible results of an RPC, parsed from a "Result:" or "Result (condition):" help section
+type Result struct {
+	Condition string      `json:"condition,omitempty"`
+	Value     ResultField `json:"value"`
+}
+
+// ResultField is a node of a result: the result value itself, an object member (with a Key) or an array element
+type ResultField struct {
+	Key         string        `json:"key,omitempty"`
+	Type        string        `json:"type"`
+	Optional    bool          `json:"optional,omitempty"`
+	Description string        `json:"description"`
+	Fields      []ResultField `json:"fields,omitempty"`
+}
+
+// ParseResults parses every result section of an RPC help text, in order
+func ParseResults(help string) ([]Result, error) {
+	sections, err := splitHelp(help)
+	if err != nil {
+		e := fmt.Errorf("failed to split help: %w", err)
+		return nil, e
+	}
+
+	var results []Result
+	for _, sec := range sections {
+		if sec.title != "Result" {
+			continue
+		}
+		value, err := parseResultSection(sec.lines)
+		if err != nil {
+			e := fmt.Errorf("failed to parse result %q: %w", sec.condition, err)
+			return nil, e
+		}
+		results = append(results, Result{Condition: sec.condition, Value: value})
+	}
+	return results, nil
+}
+
+func parseResultSection(lines []string) (ResultField, error) {
+	var root ResultField
+	entries, err := parseHelpEntries(lines)
+	if err != nil {
+		return root, err
+	}
+	if len(entries) == 0 {
+		return root, fmt.Errorf("empty result")
+	}
+
+	root.setMeta(entries[0])
+	var stack []*ResultField
+	if opensContainer(entries[0].left) {
+		stack = append(stack, &root)
+	}
+	for _, e := range entries[1:] {
+		if len(stack) == 0 {
+			return root, fmt.Errorf("entry %q follows the end of the result", e.left)
+		}
+		parent := stack[len(stack)-1]
+		switch {
+		case closesContainer(e.left):
+			stack = stack[:len(stack)-1]
+		case isElision(e.left):
+			continue
+		default:
+			key, rest := splitJsonKey(e.left)
+			f := ResultField{Key: key}
+			f.setMeta(e)
+			parent.Fields = append(parent.Fields, f)
+			if opensContainer(rest) {
+				stack = append(stack, &parent.Fields[len(parent.Fields)-1])
+			}
+		}
+	}
+	if len(stack) > 0 {
+		return root, fmt.Errorf("unclosed %q", stack[len(stack)-1].Type)
+	}
+	return root, nil
+}
+
+// setMeta fills in the field from a "(type, optional)" annotation and description
+func (f *ResultField) setMeta(e helpEntry) {
+	f.Description = e.description
+	if !e.hasMeta {
+		return
+	}
+	for i, part := range strings.Split(e.meta, ",") {
+		part = strings.TrimSpace(part)
+		switch {
+		case i == 0:
+			f.Type = part
+		case part == "optional":
+			f.Optional = true
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package bitcoind
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseResults(t *testing.T) {
+	results, err := ParseResults(getBlockHelp)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	assert.Equal(t, Result{
+		Condition: "for verbosity = 0",
+		Value:     ResultField{Type: "string", Description: "A string that is serialized, hex-encoded data for block 'hash'"},
+	}, results[0])
+
+	verbose := results[1]
+	assert.Equal(t, "for verbosity = 1", verbose.Condition)
+	assert.Equal(t, "json object", verbose.Value.Type)
+	keys := make([]string, len(verbose.Value.Fields))
+	for i, f := range verbose.Value.Fields {
+		keys[i] = f.Key
+	}
+	assert.Equal(t, []string{"hash", "confirmations", "size", "height", "tx", "time", "previousblockhash", "nextblockhash"}, keys)
+	tx := verbose.Value.Fields[4]
+	assert.Equal(t, ResultField{
+		Key:         "tx",
+		Type:        "json array",
+		Description: "The transaction ids",
+		Fields:      []ResultField{{Type: "string", Description: "The transaction id"}},
+	}, tx)
+	assert.Equal(t, ResultField{Key: "nextblockhash", Type: "string", Optional: true, Description: "The hash of the next block (if available)"}, verbose.Value.Fields[7])
+
+	txs := results[2].Value.Fields[0]
+	assert.Equal(t, "tx", txs.Key)
+	require.Len(t, txs.Fields, 1)
+	assert.Equal(t, []ResultField{{Key: "fee", Type: "numeric", Description: "The transaction fee in BTC, omitted if block undo data is not available"}}, txs.Fields[0].Fields)
+}
+
+func TestParseNullResult(t *testing.T) {
+	results, err := ParseResults("ping\n\nRequests that a ping be sent to all other nodes.\n\nResult:\nnull    (json null)\n\nExamples:\n> bitcoin-cli ping\n")
+	require.NoError(t, err)
+	assert.Equal(t, []Result{{Value: ResultField{Type: "json null"}}}, results)
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2009-2010 Satoshi Nakamoto
+// Copyright (c) 2009-2022 The Bitcoin Core developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+#include <rest.h>
+
+static bool rest_headers(const std::any& context,
+                         HTTPRequest* req,
+                         const std::string& strURIPart)
+{
+    std::string param;
+    const RESTResponseFormat rf = ParseDataFormat(param, strURIPart);
+
+    switch (rf) {
+    case RESTResponseFormat::BINARY: {
+        req->WriteReply(HTTP_OK, binaryHeader);
+        return true;
+    }
+
+    case RESTResponseFormat::HEX: {
+        req->WriteReply(HTTP_OK, strHex);
+        return true;
+    }
+    case RESTResponseFormat::JSON: {
+        req->WriteReply(HTTP_OK, strJSON);
+        return true;
+    }
+    default: {
+        return RESTERR(req, HTTP_NOT_FOUND, "output format not found (available: " + AvailableDataFormatsString() + ")");
+    }
+    }
+}
+
+static bool rest_block(const std::any& context,
+                       HTTPRequest* req,
+                       const std::string& strURIPart,
+                       TxVerbosity tx_verbosity)
+{
+    switch (rf) {
+    case RESTResponseFormat::BINARY: {
+        return true;
+    }
+
+    case RESTResponseFormat::HEX: {
+        return true;
+    }
+
+    case RESTResponseFormat::JSON: {
+        return true;
+    }
+
+    default: {
+        return RESTERR(req, HTTP_NOT_FOUND, "output format not found (available: " + AvailableDataFormatsString() + ")");
+    }
+    }
+}
+
+static bool rest_block_extended(const std::any& context, HTTPRequest* req, const std::string& strURIPart)
+{
+    return rest_block(context, req, strURIPart, TxVerbosity::SHOW_DETAILS_AND_PREVOUT);
+}
+
+static bool rest_block_notxdetails(const std::any& context, HTTPRequest* req, const std::string& strURIPart)
+{
+    return rest_block(context, req, strURIPart, TxVerbosity::SHOW_TXID);
+}
+
+static bool rest_chaininfo(const std::any& context, HTTPRequest* req, const std::string& strURIPart)
+{
+    if (!CheckWarmup(req))
+        return false;
+    std::string param;
+    const RESTResponseFormat rf = ParseDataFormat(param, strURIPart);
+
+    switch (rf) {
+    case RESTResponseFormat::JSON: {
+        return true;
+    }
+    default: {
+        return RESTERR(req, HTTP_NOT_FOUND, "output format not found (available: json)");
+    }
+    }
+}
+
+static const struct {
+    const char* prefix;
+    bool (*handler)(const std::any& context, HTTPRequest* req, const std::string& strReq);
+} uri_prefixes[] = {
+      {"/rest/block/notxdetails/", rest_block_notxdetails},
+      {"/rest/block/", rest_block_extended},
+      {"/rest/chaininfo", rest_chaininfo},
+      {"/rest/headers/", rest_headers},
+};
+
+void StartREST(const std::any& context)
+{
+    for (const auto& up : uri_prefixes) {
+        auto handler = [context, up](HTTPRequest* req, const std::string& prefix) { return up.handler(context, req, prefix); };
+        RegisterHTTPHandler(up.prefix, false, handler);
+    }
+}
@@ -0,0 +1,21 @@
+// Copyright (c) 2009-2019 The Bitcoin Core developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+#include <wallet/rpcwallet.h>
+
+UniValue importprivkey(const JSONRPCRequest& request)
+{
+    std::shared_ptr<CWallet> const wallet = GetWalletForJSONRPCRequest(request);
+    CWallet* const pwallet = wallet.get();
+    if (!EnsureWalletIsAvailable(pwallet, request.fHelp)) {
+        return NullUniValue;
+    }
+    return NullUniValue;
+}
+
+static UniValue listwallets(const JSONRPCRequest& request)
+{
+    UniValue obj(UniValue::VARR);
+    return obj;
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2010 Satoshi Nakamoto
+// Copyright (c) 2009-2022 The Bitcoin Core developers
+// Distributed under the MIT software license, see the accompanying
+// file COPYING or http://www.opensource.org/licenses/mit-license.php.
+
+#include <wallet/rpc/util.h>
+
+namespace wallet {
+
+static RPCHelpMan getwalletinfo()
+{
+    return RPCHelpMan{"getwalletinfo",
+                "Returns an object containing various wallet state info.\n",
+                {},
+                RPCResult{RPCResult::Type::OBJ, "", "", {}},
+                RPCExamples{
+                    HelpExampleCli("getwalletinfo", "")
+            + HelpExampleRpc("getwalletinfo", "")
+                },
+        [&](const RPCHelpMan& self, const JSONRPCRequest& request) -> UniValue
+{
+    const std::shared_ptr<const CWallet> pwallet = GetWalletForJSONRPCRequest(request);
+    if (!pwallet) return UniValue::VNULL;
+
+    UniValue obj(UniValue::VOBJ);
+    return obj;
+},
+    };
+}
+
+static RPCHelpMan listwalletdir()
+{
+    return RPCHelpMan{"listwalletdir",
+                "Returns a list of wallets in the wallet directory.\n",
+                {},
+                RPCResult{RPCResult::Type::OBJ, "", "", {}},
+                RPCExamples{
+                    HelpExampleCli("listwalletdir", "")
+            + HelpExampleRpc("listwalletdir", "")
+                },
+        [&](const RPCHelpMan& self, const JSONRPCRequest& request) -> UniValue
+{
+    UniValue wallets(UniValue::VARR);
+    for (const auto& path : ListDatabases(GetWalletDir())) {
+        UniValue wallet(UniValue::VOBJ);
+        wallet.pushKV("name", path.utf8string());
+        wallets.push_back(wallet);
+    }
+
+    UniValue result(UniValue::VOBJ);
+    result.pushKV("wallets", wallets);
+    return result;
+},
+    };
+}
+
+RPCHelpMan walletpassphrase()
+{
+    return RPCHelpMan{
+        "walletpassphrase",
+        "Stores the wallet decryption key in memory for 'timeout' seconds.\n",
+        {},
+        RPCResult{RPCResult::Type::NONE, "", ""},
+        RPCExamples{""},
+        [&](const RPCHelpMan& self, const JSONRPCRequest& request) -> UniValue
+{
+    std::shared_ptr<CWallet> const pwallet = GetWalletForJSONRPCRequest(request);
+    if (!pwallet) return UniValue::VNULL;
+    return UniValue::VNULL;
+},
+    };
+}
+} // namespace wallet
@@ -0,0 +1,148 @@
+package bitcoind
+
+import (
+	"bitcoinrpcschema/internal/version"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/rpcclient"
+	"log"
+	"os"
+	"path"
+	"regexp"
+)
+
+// walletDir is where the downloader puts the wallet RPC sources of a release
+const walletDir = "wallet"
+
+// createWallets creates and loads a descriptor wallet and, where still supported, a legacy wallet,
+// so that wallet commands are captured as they are seen by a node with a wallet. A wallet the node
+// refuses to create is left out, and a node without wallet support is captured without any.
+func createWallets(c *rpcclient.Client, v version.Version) error {
+	// descriptor wallets, and the descriptors param, were added in v0.21
+	if v.Cmp(version.Version{Minor: 21}) < 0 {
+		_, err := createWallet(c, "legacy")
+		return skipWallet("legacy", err)
+	}
+
+	// wallet_name, disable_private_keys, blank, passphrase, avoid_reuse, descriptors
+	_, err := createWallet(c, "descriptors", false, false, "", false, true)
+	if isMethodNotFound(err) {
+		return skipWallet("descriptor", err)
+	}
+	err = skipWallet("descriptor", err)
+	if err != nil {
+		return err
+	}
+	// legacy wallets can no longer be created since v28, so failure is expected there
+	_, err = createWallet(c, "legacy", false, false, "", false, false)
+	return skipWallet("legacy", err)
+}
+
+// skipWallet logs the node refusing to create a wallet, e.g. without wallet support or SQLite,
+// and returns any other error
+func skipWallet(kind string, err error) error {
+	var rpcErr *btcjson.RPCError
+	if !errors.As(err, &rpcErr) {
+		return err
+	}
+	if isMethodNotFound(err) {
+		log.Printf("not creating %s wallet, the node has no wallet support: %v", kind, err)
+		return nil
+	}
+	log.Printf("not creating %s wallet: %v", kind, err)
+	return nil
+}
+
+// isMethodNotFound tells whether err is the RPC error of a node built or started without its wallet
+func isMethodNotFound(err error) bool {
+	var rpcErr *btcjson.RPCError
+	return errors.As(err, &rpcErr) && rpcErr.Code == btcjson.ErrRPCMethodNotFound.Code
+}
+
+func createWallet(c *rpcclient.Client, params ...any) (json.RawMessage, error) {
+	rawParams := make([]json.RawMessage, len(params))
+	for i, p := range params {
+		b, err := json.Marshal(p)
+		if err != nil {
+			return nil, err
+		}
+		rawParams[i] = b
+	}
+	return c.RawRequest("createwallet", rawParams)
+}
+
+// walletRpcRe matches the start of each RPC definition: RPCHelpMan since v0.21, and plain functions before
+var walletRpcRe = regexp.MustCompile(`RPCHelpMan\{\s*"(\w+)"|(?m)^(?:static )?UniValue (\w+)\(const JSONRPCRequest& request\)`)
+
+var walletLookupRe = regexp.MustCompile(`GetWalletForJSONRPCRequest\(`)
+
+// getWalletCommands finds the commands that need a loaded wallet in the wallet RPC sources of a release,
+// i.e. those that look up the wallet of the request.
+func getWalletCommands(dir string) ([]string, error) {
+	walletPath := path.Join(dir, walletDir)
+	ps, err := os.ReadDir(walletPath)
+	if err != nil {
+		e := fmt.Errorf("failed to read dir: %w", err)
+		return nil, e
+	}
+
+	var cmds []string
+	for _, p := range ps {
+		if p.IsDir() || path.Ext(p.Name()) != ".cpp" {
+			continue
+		}
+		content, err := os.ReadFile(path.Join(walletPath, p.Name()))
+		if err != nil {
+			e := fmt.Errorf("failed to read file: %w", err)
+			return nil, e
+		}
+		cmds = append(cmds, walletRequiringCmds(content)...)
+	}
+	return cmds, nil
+}
+
+func walletRequiringCmds(cpp []byte) []string {
+	var cmds []string
+	matches := walletRpcRe.FindAllSubmatchIndex(cpp, -1)
+	for i, m := range matches {
+		end := len(cpp)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		var name []byte
+		if m[2] >= 0 {
+			name = cpp[m[2]:m[3]]
+		} else {
+			name = cpp[m[4]:m[5]]
+		}
+		body := cpp[m[1]:end]
+		if walletLookupRe.Match(body) {
+			cmds = append(cmds, string(name))
+		}
+	}
+	return cmds
+}
+
+// markWalletCommands sets RequiresWallet on the commands found in the wallet sources of the release
+func markWalletCommands(dir string, cmds map[string][]Command) error {
+	walletCmds, err := getWalletCommands(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Printf("no wallet sources in %s, not marking wallet commands", dir)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	requiresWallet := make(map[string]bool, len(walletCmds))
+	for _, name := range walletCmds {
+		requiresWallet[name] = true
+	}
+	for _, sectionCmds := range cmds {
+		for i := range sectionCmds {
+			sectionCmds[i].RequiresWallet = requiresWallet[sectionCmds[i].Name]
+		}
+	}
+	return nil
+}
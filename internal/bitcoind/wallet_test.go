@@ -0,0 +1,73 @@
+package bitcoind
+
+import (
+	"bitcoinrpcschema/internal/version"
+	_ "embed"
+	"encoding/json"
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+//go:embed test/wallet.cpp
+var walletCpp []byte
+
+//go:embed test/rpcdump.cpp
+var rpcDumpCpp []byte
+
+func TestWalletRequiringCmds(t *testing.T) {
+	assert.Equal(t, []string{"getwalletinfo", "walletpassphrase"}, walletRequiringCmds(walletCpp))
+}
+
+func TestWalletRequiringCmdsBeforeRpcHelpMan(t *testing.T) {
+	assert.Equal(t, []string{"importprivkey"}, walletRequiringCmds(rpcDumpCpp))
+}
+
+// fakeWalletNode answers createwallet with the given JSON-RPC error, and records the params of each call
+func fakeWalletNode(t *testing.T, rpcErr string) (*rpcclient.Client, *[][]json.RawMessage) {
+	var calls [][]json.RawMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Id     json.RawMessage   `json:"id"`
+			Params []json.RawMessage `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		calls = append(calls, req.Params)
+		_, _ = w.Write([]byte(`{"result":null,"error":` + rpcErr + `,"id":` + string(req.Id) + `}`))
+	}))
+	t.Cleanup(srv.Close)
+	c, err := rpcclient.New(&rpcclient.ConnConfig{
+		Host:         strings.TrimPrefix(srv.URL, "http://"),
+		User:         "user",
+		Pass:         "pass",
+		DisableTLS:   true,
+		HTTPPostMode: true,
+	}, nil)
+	require.NoError(t, err)
+	t.Cleanup(c.Shutdown)
+	return c, &calls
+}
+
+func TestCreateWalletsWithoutWallet(t *testing.T) {
+	c, calls := fakeWalletNode(t, `{"code":-32601,"message":"Method not found"}`)
+	require.NoError(t, createWallets(c, version.Version{Major: 27}))
+	assert.Len(t, *calls, 1)
+}
+
+func TestCreateWalletsWithoutDescriptors(t *testing.T) {
+	c, calls := fakeWalletNode(t, `{"code":-4,"message":"Compiled without sqlite support (required for descriptor wallets)"}`)
+	require.NoError(t, createWallets(c, version.Version{Major: 22}))
+	require.Len(t, *calls, 2)
+	assert.Len(t, (*calls)[1], 6)
+}
+
+func TestCreateWalletsBeforeDescriptors(t *testing.T) {
+	c, calls := fakeWalletNode(t, `null`)
+	require.NoError(t, createWallets(c, version.Version{Minor: 20, Patch: 1}))
+	require.Len(t, *calls, 1)
+	assert.Equal(t, []json.RawMessage{json.RawMessage(`"legacy"`)}, (*calls)[0])
+}
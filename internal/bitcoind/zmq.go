@@ -0,0 +1,164 @@
+package bitcoind
+
+import (
+	"bitcoinrpcschema/internal/version"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/btcsuite/btcd/rpcclient"
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// zmqDir is where the downloader puts the ZMQ sources of a release
+const zmqDir = "zmq"
+
+// ZmqTopic is a ZMQ notification that bitcoind can publish
+type ZmqTopic struct {
+	Topic       string `json:"topic"`
+	Option      string `json:"option"`
+	Description string `json:"description"`
+	// Body describes the message part between the topic and the 4-byte sequence number
+	Body   string     `json:"body,omitempty"`
+	Labels []ZmqLabel `json:"labels,omitempty"`
+	// Reported is set when getzmqnotifications listed the topic for a node publishing it, with its Hwm
+	Reported bool `json:"reported,omitempty"`
+	Hwm      int  `json:"hwm,omitempty"`
+}
+
+// ZmqLabel is a label of the sequence topic, like C for a connected block
+type ZmqLabel struct {
+	Label       string `json:"label"`
+	Description string `json:"description"`
+}
+
+// zmqBody is the body of a topic's messages, from the release it was introduced in
+type zmqBody struct {
+	since version.Version
+	body  string
+}
+
+// zmqBodies are the message bodies as documented in doc/zmq.md, each topic's in the order they were introduced
+var zmqBodies = map[string][]zmqBody{
+	"hashblock": {{version.Version{Minor: 12}, "32-byte block hash, in reversed byte order"}},
+	"hashtx":    {{version.Version{Minor: 12}, "32-byte transaction hash, in reversed byte order"}},
+	"rawblock":  {{version.Version{Minor: 12}, "serialized block"}},
+	"rawtx":     {{version.Version{Minor: 12}, "serialized transaction"}},
+	"sequence": {{version.Version{Minor: 21}, "32-byte hash in reversed byte order, a 1-byte label and, " +
+		"for mempool labels, an 8-byte little-endian mempool sequence number"}},
+}
+
+// setZmqBodies fills in the body of each topic's messages in release v
+func setZmqBodies(topics []ZmqTopic, v version.Version) {
+	for i := range topics {
+		for _, b := range zmqBodies[topics[i].Topic] {
+			if v.Cmp(b.since) >= 0 {
+				topics[i].Body = b.body
+			}
+		}
+	}
+}
+
+var zmqOptionRe = regexp.MustCompile(`^zmqpub(\w+)$`)
+
+// zmqTopics lists the ZMQ topics of the -zmqpub<topic> options, leaving out the -zmqpub<topic>hwm options
+func zmqTopics(groups []OptionGroup) []ZmqTopic {
+	var topics []ZmqTopic
+	for _, g := range groups {
+		for _, o := range g.Options {
+			m := zmqOptionRe.FindStringSubmatch(o.Name)
+			if m == nil || strings.HasSuffix(m[1], "hwm") {
+				continue
+			}
+			topics = append(topics, ZmqTopic{Topic: m[1], Option: o.Name, Description: o.Description})
+		}
+	}
+	return topics
+}
+
+// zmqArgs are the bitcoind arguments to publish every topic on its free local port
+func zmqArgs(topics []ZmqTopic, ports []int) []string {
+	args := make([]string, len(topics))
+	for i, t := range topics {
+		args[i] = fmt.Sprintf("-%s=tcp://127.0.0.1:%d", t.Option, ports[i])
+	}
+	return args
+}
+
+type zmqNotification struct {
+	Type    string `json:"type"`
+	Address string `json:"address"`
+	Hwm     int    `json:"hwm"`
+}
+
+func getZmqNotifications(c *rpcclient.Client) ([]zmqNotification, error) {
+	res, err := c.RawRequest("getzmqnotifications", nil)
+	if err != nil {
+		return nil, err
+	}
+	var notifications []zmqNotification
+	err = json.Unmarshal(res, &notifications)
+	if err != nil {
+		e := fmt.Errorf("failed to decode ZMQ notifications: %w", err)
+		return nil, e
+	}
+	return notifications, nil
+}
+
+// setZmqNotifications marks the topics reported by getzmqnotifications, with their high water mark
+func setZmqNotifications(topics []ZmqTopic, notifications []zmqNotification) {
+	for i := range topics {
+		for _, n := range notifications {
+			if n.Type == "pub"+topics[i].Topic {
+				topics[i].Reported = true
+				topics[i].Hwm = n.Hwm
+			}
+		}
+	}
+}
+
+var zmqMsgRe = regexp.MustCompile(`static const char \*MSG_\w+\s*=\s*"(\w+)";`)
+var zmqLabelRe = regexp.MustCompile(`/\*\s*([^*]+?)\s*\*/\s*'([A-Z])'`)
+
+// ParseZmqSource finds the topics and sequence labels in src/zmq/zmqpublishnotifier.cpp
+func ParseZmqSource(cpp []byte) ([]string, []ZmqLabel) {
+	var topics []string
+	for _, m := range zmqMsgRe.FindAllSubmatch(cpp, -1) {
+		topics = append(topics, string(m[1]))
+	}
+	var labels []ZmqLabel
+	for _, m := range zmqLabelRe.FindAllSubmatch(cpp, -1) {
+		l := ZmqLabel{Label: string(m[2]), Description: string(m[1])}
+		if !slices.Contains(labels, l) {
+			labels = append(labels, l)
+		}
+	}
+	return topics, labels
+}
+
+// addZmqSource adds the sequence labels from the ZMQ sources of a release, and checks they publish every topic
+func addZmqSource(dir string, topics []ZmqTopic) error {
+	cpp, err := os.ReadFile(path.Join(dir, zmqDir, "zmqpublishnotifier.cpp"))
+	if errors.Is(err, os.ErrNotExist) {
+		log.Printf("no ZMQ sources in %s", dir)
+		return nil
+	}
+	if err != nil {
+		e := fmt.Errorf("failed to read file: %w", err)
+		return e
+	}
+	published, labels := ParseZmqSource(cpp)
+	for i, t := range topics {
+		if !slices.Contains(published, t.Topic) {
+			log.Printf("ZMQ topic %s of -%s not found in the sources of %s", t.Topic, t.Option, dir)
+		}
+		if t.Topic == "sequence" {
+			topics[i].Labels = labels
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package bitcoind
+
+import (
+	"bitcoinrpcschema/internal/version"
+	_ "embed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+//go:embed test/zmqpublishnotifier.cpp
+var zmqPublishNotifierCpp []byte
+
+func TestParseZmqSource(t *testing.T) {
+	topics, labels := ParseZmqSource(zmqPublishNotifierCpp)
+	assert.Equal(t, []string{"hashblock", "hashtx", "rawblock", "rawtx", "sequence"}, topics)
+	expected := []ZmqLabel{
+		{Label: "C", Description: "Block (C)onnect"},
+		{Label: "D", Description: "Block (D)isconnect"},
+		{Label: "A", Description: "Mempool (A)cceptance"},
+		{Label: "R", Description: "Mempool (R)emoval"},
+	}
+	assert.Equal(t, expected, labels)
+}
+
+func TestZmqTopics(t *testing.T) {
+	groups := []OptionGroup{{Name: "ZeroMQ notification options", Options: []ConfigOption{
+		{Name: "zmqpubhashblock", Value: "<address>", Description: "Enable publish hash block in <address>"},
+		{Name: "zmqpubhashblockhwm", Value: "<n>", Default: "1000", Description: "Set publish hash block outbound message high water mark (default: 1000)"},
+		{Name: "zmqpubsequence", Value: "<address>", Description: "Enable publish hash block and tx sequence in <address>"},
+	}}}
+	topics := zmqTopics(groups)
+	require.Len(t, topics, 2)
+	assert.Equal(t, "hashblock", topics[0].Topic)
+	assert.Equal(t, "zmqpubhashblock", topics[0].Option)
+	assert.Equal(t, "sequence", topics[1].Topic)
+
+	setZmqNotifications(topics, []zmqNotification{{Type: "pubsequence", Address: "tcp://127.0.0.1:28332", Hwm: 1000}})
+	assert.False(t, topics[0].Reported)
+	assert.True(t, topics[1].Reported)
+	assert.Equal(t, 1000, topics[1].Hwm)
+}
+
+func TestZmqBodies(t *testing.T) {
+	topics := []ZmqTopic{{Topic: "hashblock"}, {Topic: "sequence"}, {Topic: "unknown"}}
+	setZmqBodies(topics, version.Version{Minor: 20})
+	assert.Equal(t, "32-byte block hash, in reversed byte order", topics[0].Body)
+	assert.Empty(t, topics[1].Body)
+	assert.Empty(t, topics[2].Body)
+
+	setZmqBodies(topics, version.Version{Major: 27})
+	assert.NotEmpty(t, topics[1].Body)
+}
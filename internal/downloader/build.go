@@ -0,0 +1,215 @@
+package downloader
+
+import (
+	"bitcoinrpcschema/internal/version"
+	"errors"
+	"fmt"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+)
+
+// BuildOptions control how bitcoind is built from a git ref
+type BuildOptions struct {
+	// Jobs is the number of parallel build jobs, the number of CPUs if 0
+	Jobs int
+	// ConfigureArgs are passed to cmake or ./configure, after the defaults
+	ConfigureArgs []string
+}
+
+// binaries are the programs copied out of a build, like those extracted from a release
+var binaries = []string{"bitcoind", "bitcoin-cli", "bitcoin-wallet", "bitcoin-tx", "bitcoin-util"}
+
+var labelUnsafeRe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Build builds bitcoind and its tools from a ref of the local repo at repoPath, into
+// rootPath/bitcoin-<label>/bin alongside the sources of the RPCs, like a release. The label is
+// the ref and the short commit hash, like master-1a2b3c4. It returns the label.
+func Build(rootPath, repoPath, ref string, opts BuildOptions) (string, error) {
+	r, err := git.PlainOpenWithOptions(repoPath, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return "", fmt.Errorf("failed to open bitcoin repo %s: %w", repoPath, err)
+	}
+	h, err := r.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", ref, err)
+	}
+	commit, err := r.CommitObject(*h)
+	if err != nil {
+		return "", fmt.Errorf("failed to get commit %s: %w", h, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return "", fmt.Errorf("failed to get tree of %s: %w", h, err)
+	}
+	label := refLabel(ref, *h)
+
+	srcDir, err := os.MkdirTemp("", "bitcoinrpcschema-build")
+	if err != nil {
+		return "", fmt.Errorf("error creating build dir: %w", err)
+	}
+	defer removeAll(srcDir)
+	slog.Info(fmt.Sprintf("checking out %s as %s", ref, label))
+	err = exportTree(tree, srcDir)
+	if err != nil {
+		return "", err
+	}
+
+	binDir, err := build(srcDir, opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to build %s: %w", label, err)
+	}
+
+	dir := filepath.Join(rootPath, "bitcoin-"+label)
+	err = os.MkdirAll(filepath.Join(dir, "bin"), 0755)
+	if err != nil {
+		return "", err
+	}
+	for _, name := range binaries {
+		err = copyFile(filepath.Join(binDir, name), filepath.Join(dir, "bin", name))
+		if errors.Is(err, os.ErrNotExist) && name != "bitcoind" {
+			slog.Warn(fmt.Sprintf("%s wasn't built", name))
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	files, err := getTreeRpcCppFiles(tree)
+	if err != nil {
+		return "", fmt.Errorf("failed to get rpc cpp files for %s: %w", label, err)
+	}
+	err = writeRpcFiles(dir, files)
+	if err != nil {
+		return "", err
+	}
+	err = os.WriteFile(filepath.Join(dir, version.LabelFile), []byte(label+"\n"), 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to write label: %w", err)
+	}
+	return label, nil
+}
+
+// refLabel names a build after the last part of its ref, like 29.x for origin/29.x, and its short hash
+func refLabel(ref string, h plumbing.Hash) string {
+	name := labelUnsafeRe.ReplaceAllString(filepath.Base(ref), "-")
+	short := h.String()[:7]
+	if name == "" || name == "-" || name == short || name == h.String() {
+		return short
+	}
+	return name + "-" + short
+}
+
+// exportTree writes the files of tree into dir
+func exportTree(tree *object.Tree, dir string) error {
+	return tree.Files().ForEach(func(f *object.File) error {
+		p := filepath.Join(dir, filepath.FromSlash(f.Name))
+		err := os.MkdirAll(filepath.Dir(p), 0755)
+		if err != nil {
+			return err
+		}
+		if f.Mode == filemode.Symlink {
+			target, err := f.Contents()
+			if err != nil {
+				return fmt.Errorf("failed to read symlink %s: %w", f.Name, err)
+			}
+			return os.Symlink(target, p)
+		}
+		perm := os.FileMode(0644)
+		if f.Mode == filemode.Executable {
+			perm = 0755
+		}
+		r, err := f.Reader()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		defer silentClose(r)
+		out, err := os.OpenFile(p, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out, r)
+		closeErr := out.Close()
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", p, err)
+		}
+		return closeErr
+	})
+}
+
+// buildCommands are the commands building the sources in srcDir with the wallet and ZMQ, with CMake since v29
+// and autotools before. It returns them with the directory the binaries end up in.
+func buildCommands(srcDir string, opts BuildOptions) ([][]string, string, error) {
+	jobs := opts.Jobs
+	if jobs == 0 {
+		jobs = runtime.NumCPU()
+	}
+	j := "-j" + strconv.Itoa(jobs)
+
+	if _, err := os.Stat(filepath.Join(srcDir, "CMakeLists.txt")); err == nil {
+		configure := append([]string{"cmake", "-B", "build", "-DENABLE_WALLET=ON", "-DWITH_ZMQ=ON",
+			"-DBUILD_GUI=OFF", "-DBUILD_TESTS=OFF", "-DBUILD_BENCH=OFF", "-DBUILD_FUZZ_BINARY=OFF",
+			"-DBUILD_CLI=ON", "-DBUILD_TX=ON", "-DBUILD_UTIL=ON", "-DBUILD_WALLET_TOOL=ON"}, opts.ConfigureArgs...)
+		return [][]string{configure, {"cmake", "--build", "build", j}}, filepath.Join(srcDir, "build", "bin"), nil
+	}
+	if _, err := os.Stat(filepath.Join(srcDir, "autogen.sh")); err == nil {
+		configure := append([]string{"./configure", "--enable-wallet", "--with-zmq", "--without-gui",
+			"--disable-tests", "--disable-bench", "--disable-fuzz-binary", "--with-utils"}, opts.ConfigureArgs...)
+		return [][]string{{"./autogen.sh"}, configure, {"make", j}}, filepath.Join(srcDir, "src"), nil
+	}
+	return nil, "", fmt.Errorf("%s has neither CMakeLists.txt nor autogen.sh", srcDir)
+}
+
+func build(srcDir string, opts BuildOptions) (string, error) {
+	cmds, binDir, err := buildCommands(srcDir, opts)
+	if err != nil {
+		return "", err
+	}
+	for _, args := range cmds {
+		slog.Info(fmt.Sprintf("running %v", args))
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Dir = srcDir
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		err := cmd.Run()
+		if err != nil {
+			return "", fmt.Errorf("%v failed: %w", args, err)
+		}
+	}
+	return binDir, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer silentClose(in)
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	closeErr := out.Close()
+	if err != nil {
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	return closeErr
+}
+
+func removeAll(dir string) {
+	err := os.RemoveAll(dir)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("failed to remove %s: %v", dir, err))
+	}
+}
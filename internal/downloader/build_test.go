@@ -0,0 +1,57 @@
+package downloader
+
+import (
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRefLabel(t *testing.T) {
+	h := plumbing.NewHash("1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d")
+	assert.Equal(t, "master-1a2b3c4", refLabel("master", h))
+	assert.Equal(t, "29.x-1a2b3c4", refLabel("origin/29.x", h))
+	assert.Equal(t, "HEAD-1-1a2b3c4", refLabel("HEAD~1", h))
+	assert.Equal(t, "1a2b3c4", refLabel("1a2b3c4", h))
+}
+
+func TestBuildCommands(t *testing.T) {
+	dir := t.TempDir()
+	_, _, err := buildCommands(dir, BuildOptions{})
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "autogen.sh"), nil, 0755))
+	cmds, binDir, err := buildCommands(dir, BuildOptions{Jobs: 2, ConfigureArgs: []string{"--without-bdb"}})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "src"), binDir)
+	assert.Equal(t, []string{"./autogen.sh"}, cmds[0])
+	assert.Contains(t, cmds[1], "--with-zmq")
+	assert.Equal(t, "--without-bdb", cmds[1][len(cmds[1])-1])
+	assert.Equal(t, []string{"make", "-j2"}, cmds[2])
+
+	// CMake replaced autotools in v29
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "CMakeLists.txt"), nil, 0644))
+	cmds, binDir, err = buildCommands(dir, BuildOptions{Jobs: 2})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "build", "bin"), binDir)
+	assert.Contains(t, cmds[0], "-DWITH_ZMQ=ON")
+	assert.Equal(t, []string{"cmake", "--build", "build", "-j2"}, cmds[1])
+}
+
+func TestExportTree(t *testing.T) {
+	_, r := newTestRepo(t)
+	head, err := r.Head()
+	require.NoError(t, err)
+	commit, err := r.CommitObject(head.Hash())
+	require.NoError(t, err)
+	tree, err := commit.Tree()
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, exportTree(tree, dir))
+	b, err := os.ReadFile(filepath.Join(dir, "src", "wallet", "rpcwallet.cpp"))
+	require.NoError(t, err)
+	assert.Equal(t, "rpcwallet", string(b))
+}
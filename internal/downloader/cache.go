@@ -0,0 +1,162 @@
+package downloader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cache keeps downloads on disk, so that they are only fetched once:
+//
+//	tarballs/<sha256>  release tarballs, by content
+//	meta/<host>/<path> release listings and SHA256SUMS
+//	bitcoin.git        bare mirror of the bitcoin repo
+type cache struct {
+	dir     string
+	offline bool
+}
+
+func (c cache) tarballsDir() string {
+	return filepath.Join(c.dir, "tarballs")
+}
+
+func (c cache) gitDir() string {
+	return filepath.Join(c.dir, "bitcoin.git")
+}
+
+func (c cache) metaPath(u string) (string, error) {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return "", fmt.Errorf("invalid url %s: %w", u, err)
+	}
+	p := parsed.Path
+	if p == "" || strings.HasSuffix(p, "/") {
+		p += "index.html"
+	}
+	return filepath.Join(c.dir, "meta", parsed.Host, filepath.FromSlash(p)), nil
+}
+
+// get returns the body of u. A cached copy is used even when online, unless refresh is set: the release
+// listings change, and so does SHA256SUMS.asc as builders add their signatures after a release.
+func (c cache) get(u string, refresh bool) ([]byte, error) {
+	p, err := c.metaPath(u)
+	if err != nil {
+		return nil, err
+	}
+	if !refresh || c.offline {
+		b, err := os.ReadFile(p)
+		if err == nil {
+			return b, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("error reading cached %s: %w", u, err)
+		}
+		if c.offline {
+			return nil, errorNotFound{release: u + " (offline and not cached)"}
+		}
+	}
+
+	b, err := getBody(u)
+	if err != nil {
+		return nil, err
+	}
+	err = writeFileAtomic(p, func(w io.Writer) error {
+		_, err := w.Write(b)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error caching %s: %w", u, err)
+	}
+	return b, nil
+}
+
+// cached tells whether u is in the cache
+func (c cache) cached(u string) bool {
+	p, err := c.metaPath(u)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(p)
+	return err == nil
+}
+
+// tarball opens the cached tarball with the given hex SHA256, downloading it from u if needed
+func (c cache) tarball(u, sum string) (*os.File, error) {
+	p := filepath.Join(c.tarballsDir(), sum)
+	cached, err := fileSha256(p)
+	if err == nil && cached == sum {
+		slog.Info("using cached " + u)
+		return os.Open(p)
+	}
+	if err == nil {
+		slog.Warn(fmt.Sprintf("cached tarball %s is corrupt, removing it", p))
+		err = os.Remove(p)
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("error reading cached tarball: %w", err)
+	}
+	if c.offline {
+		return nil, errorNotFound{release: u + " (offline and not cached)"}
+	}
+
+	err = writeFileAtomic(p, func(w io.Writer) error {
+		actual, err := download(u, w)
+		if err != nil {
+			return err
+		}
+		if actual != sum {
+			return fmt.Errorf("refusing to extract %s: its SHA256 is %s, but SHA256SUMS has %s", u, actual, sum)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+// writeFileAtomic writes p through a temp file, so that an interrupted download never leaves a partial file
+func writeFileAtomic(p string, write func(w io.Writer) error) error {
+	err := os.MkdirAll(filepath.Dir(p), 0755)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".download-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	err = write(tmp)
+	closeErr := tmp.Close()
+	if err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+// fileSha256 returns the hex SHA256 of the file at p
+func fileSha256(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer silentClose(f)
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,78 @@
+package downloader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	const tarball = "not really a tarball"
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/bin/bitcoin-core-27.0/SHA256SUMS":
+			_, _ = w.Write([]byte(testSums))
+		case "/bin/bitcoin-core-27.0/bitcoin.tar.gz":
+			_, _ = w.Write([]byte(tarball))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := cache{dir: t.TempDir()}
+	sumsUrl := srv.URL + "/bin/bitcoin-core-27.0/SHA256SUMS"
+	for range 2 {
+		b, err := c.get(sumsUrl, false)
+		require.NoError(t, err)
+		assert.Equal(t, testSums, string(b))
+	}
+	assert.Equal(t, int32(1), requests.Load())
+
+	_, err := c.get(srv.URL+"/bin/bitcoin-core-27.0/SHA256SUMS.asc", false)
+	var notFound errorNotFound
+	assert.True(t, errors.As(err, &notFound))
+
+	h := sha256.Sum256([]byte(tarball))
+	sum := hex.EncodeToString(h[:])
+	tarballUrl := srv.URL + "/bin/bitcoin-core-27.0/bitcoin.tar.gz"
+	_, err = c.tarball(tarballUrl, "0123abcd")
+	assert.ErrorContains(t, err, "refusing to extract")
+	f, err := c.tarball(tarballUrl, sum)
+	require.NoError(t, err)
+	b, err := io.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, tarball, string(b))
+	require.NoError(t, f.Close())
+
+	// a corrupt tarball is downloaded again
+	require.NoError(t, os.WriteFile(filepath.Join(c.tarballsDir(), sum), []byte("corrupt"), 0644))
+	requests.Store(0)
+	f, err = c.tarball(tarballUrl, sum)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	assert.Equal(t, int32(1), requests.Load())
+
+	// offline, only the cache is used
+	srv.Close()
+	offline := cache{dir: c.dir, offline: true}
+	b, err = offline.get(sumsUrl, true)
+	require.NoError(t, err)
+	assert.Equal(t, testSums, string(b))
+	f, err = offline.tarball(tarballUrl, sum)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	_, err = offline.get(srv.URL+"/bin/bitcoin-core-28.0/SHA256SUMS", false)
+	assert.True(t, errors.As(err, &notFound))
+}
@@ -2,9 +2,14 @@ package downloader
 
 import (
 	"archive/tar"
+	"bitcoinrpcschema/internal/version"
+	"bytes"
 	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/panjf2000/ants/v2"
 	"golang.org/x/net/html"
 	"io"
@@ -14,140 +19,167 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
-	"strconv"
+	"slices"
+	"strings"
 	"sync"
 )
 
-// how many major versions to keep
-const keepVersions = 3
-
 // seems reasonable
 const maxDownloadStreams = 3
 
-type releaseVersion struct {
-	major uint
-	minor uint
-	patch uint
+// Options control how releases are downloaded
+type Options struct {
+	// KeyringPath is an armored keyring file, or a directory of them such as guix.sigs/builder-keys
+	KeyringPath string
+	// MinSignatures is how many keys of the keyring must have signed SHA256SUMS, at least 1 unless signatures
+	// are skipped. Releases before v22 have a single signature, which must be from the keyring.
+	MinSignatures int
+	// SkipSignatures only checks tarballs against SHA256SUMS, without verifying who signed it
+	SkipSignatures bool
+	// CacheDir keeps tarballs, release metadata and a mirror of the bitcoin repo between runs.
+	// Without it, everything is downloaded into a temp dir.
+	CacheDir string
+	// Offline only uses what is in CacheDir
+	Offline bool
+	// Select chooses the releases to download
+	Select Selection
+	// Platform is the triple of the releases, one of Platforms. It defaults to DefaultPlatform.
+	Platform string
 }
 
-func (rv releaseVersion) String() string {
-	if rv.patch == 0 {
-		return fmt.Sprintf("%d.%d", rv.major, rv.minor)
+// releaseDir is the path of a release under the bin url, release candidates being in test.rcN directories of their release
+func releaseDir(v version.Version) string {
+	release := v
+	release.Pre = ""
+	d := fmt.Sprintf("bitcoin-core-%s/", release)
+	if rc := v.RC(); rc > 0 {
+		d += fmt.Sprintf("test.rc%d/", rc)
 	}
-	return fmt.Sprintf("%d.%d.%d", rv.major, rv.minor, rv.patch)
+	return d
 }
 
-func (rv releaseVersion) cmp(other releaseVersion) int {
-	if rv.major > other.major {
-		return 1
-	}
-	if rv.major < other.major {
-		return -1
-	}
-	if rv.minor > other.minor {
-		return 1
-	}
-	if rv.minor < other.minor {
-		return -1
-	}
-	if rv.patch > other.patch {
-		return 1
+var releaseCandidateRe = regexp.MustCompile(`^test\.(rc\d+)/$`)
+
+// parseReleaseVersion parses the directory of a release, like bitcoin-core-27.0/
+func parseReleaseVersion(s string) (version.Version, error) {
+	if !strings.HasPrefix(s, "bitcoin-core-") || !strings.HasSuffix(s, "/") {
+		return version.Version{}, fmt.Errorf("invalid release version: %s", s)
 	}
-	if rv.patch < other.patch {
-		return -1
+	v, err := version.ParseDir(s)
+	if err == nil && !v.IsRelease() {
+		// release candidates are listed in test.rcN directories of their release
+		return version.Version{}, fmt.Errorf("unexpected pre-release directory: %s", s)
 	}
-	return 0
+	return v, err
 }
 
-var releaseVersionRe = regexp.MustCompile(`^bitcoin-core-(\d+)\.(\d+)\.?(\d+)?/$`)
+func Get(rootPath, binUrl, gitUrl string, opts Options) error {
+	var keyring openpgp.EntityList
+	var err error
+	if !opts.SkipSignatures {
+		if opts.KeyringPath == "" {
+			return errors.New("no builder keyring to verify releases with, pass one or skip signature verification")
+		}
+		if opts.MinSignatures < 1 {
+			return fmt.Errorf("need at least 1 signature from the keyring, got %d, or skip signature verification", opts.MinSignatures)
+		}
+		keyring, err = readKeyring(opts.KeyringPath)
+		if err != nil {
+			return err
+		}
+	}
 
-func parseReleaseVersion(s string) (releaseVersion, error) {
-	var releaseVersion releaseVersion
-	matches := releaseVersionRe.FindStringSubmatch(s)
-	if len(matches) < 3 {
-		return releaseVersion, fmt.Errorf("invalid release version: %s", s)
+	if opts.Platform == "" {
+		opts.Platform, err = DefaultPlatform()
+		if err != nil {
+			return err
+		}
 	}
-	rvMaj, err := strconv.Atoi(matches[1])
-	if err != nil {
-		e := fmt.Errorf("invalid release version, error parsing major version: %s, %w", s, err)
-		return releaseVersion, e
+	if !slices.Contains(Platforms, opts.Platform) {
+		return fmt.Errorf("unknown platform %s, expected one of %v", opts.Platform, Platforms)
 	}
-	releaseVersion.major = uint(rvMaj)
-	rvMin, err := strconv.Atoi(matches[2])
+
+	sel, err := opts.Select.compile()
 	if err != nil {
-		e := fmt.Errorf("invalid release version, error parsing minor version: %s, %w", s, err)
-		return releaseVersion, e
+		return err
 	}
-	releaseVersion.minor = uint(rvMin)
-	if len(matches) == 4 && matches[3] != "" {
-		rvPatch, err := strconv.Atoi(matches[3])
+
+	c := cache{dir: opts.CacheDir, offline: opts.Offline}
+	if c.dir == "" {
+		if opts.Offline {
+			return errors.New("offline mode needs a cache dir")
+		}
+		tmpDir, err := os.MkdirTemp("", "bitcoinrpcschema-download")
 		if err != nil {
-			e := fmt.Errorf("invalid release version, error parsing patch version: %s, %w", s, err)
-			return releaseVersion, e
+			return fmt.Errorf("error creating temp cache dir: %w", err)
 		}
-		releaseVersion.patch = uint(rvPatch)
+		defer func() {
+			_ = os.RemoveAll(tmpDir)
+		}()
+		c.dir = tmpDir
 	}
-	return releaseVersion, nil
-}
 
-func Get(rootPath, binUrl, gitUrl string) error {
-	r, err := http.Get(binUrl)
+	index, err := c.get(binUrl, true)
 	if err != nil {
-		return err
+		return fmt.Errorf("error listing releases: %w", err)
 	}
-	defer func(Body io.ReadCloser) {
-		_ = Body.Close()
-	}(r.Body)
-
-	var versions []releaseVersion
-	var latestVersion releaseVersion
-	doc := html.NewTokenizer(r.Body)
-	for tokenType := doc.Next(); tokenType != html.ErrorToken; {
-		token := doc.Token()
-		if tokenType == html.StartTagToken && token.Data == "a" {
-			for _, attr := range token.Attr {
-				if attr.Key == "href" {
-					v, err := parseReleaseVersion(attr.Val)
-					if err == nil {
-						versions = append(versions, v)
-						if latestVersion.cmp(v) < 0 {
-							latestVersion = v
-						}
-					} else {
-						slog.Debug(fmt.Sprintf("error parsing release version: %s\n", err))
-					}
-				}
-			}
+
+	var versions []version.Version
+	for _, href := range hrefs(index) {
+		v, err := parseReleaseVersion(href)
+		if err == nil {
+			versions = append(versions, v)
+		} else {
+			slog.Debug(fmt.Sprintf("error parsing release version: %s\n", err))
 		}
-		tokenType = doc.Next()
 	}
+	versions = sel.choose(versions, true)
 
-	minMajor := latestVersion.major - keepVersions + 1
-	const approxReleasesPerMajorVersion = 4
-	keptVersions := make([]releaseVersion, 0, keepVersions*approxReleasesPerMajorVersion)
-	for _, version := range versions {
-		if version.major >= minMajor {
-			keptVersions = append(keptVersions, version)
+	var rcs []version.Version
+	for _, v := range versions {
+		if !sel.listsRCs(v) {
+			continue
 		}
+		listing, err := c.get(binUrl+releaseDir(v), true)
+		if err != nil {
+			slog.Debug(fmt.Sprintf("error listing release candidates of %s: %s\n", v, err))
+			continue
+		}
+		for _, href := range hrefs(listing) {
+			rc := releaseCandidateRe.FindStringSubmatch(href)
+			if rc == nil {
+				continue
+			}
+			v.Pre = rc[1]
+			rcs = append(rcs, v)
+		}
+	}
+	versions = append(versions, rcs...)
+	keptVersions := sel.choose(versions, false)
+	if missing := sel.missing(keptVersions); len(missing) > 0 {
+		return fmt.Errorf("versions not found or excluded by the other criteria: %v", missing)
 	}
 
 	slog.Info(fmt.Sprintf("versions to download: %v\n", keptVersions))
 
 	var wg sync.WaitGroup
-	downloadedVersions := make([]releaseVersion, 0, len(keptVersions))
+	var mu sync.Mutex
+	downloadedVersions := make([]version.Version, 0, len(keptVersions))
 	errs := make([]error, 0, len(keptVersions))
 	p, err := ants.NewPoolWithFunc(maxDownloadStreams, func(i interface{}) {
 		defer wg.Done()
-		err := downloadRelease(rootPath, binUrl, i.(releaseVersion))
-		_, ok := err.(errorNotFound)
-		if ok {
-			slog.Info(fmt.Sprintf("release unavailable: %s\n", i.(releaseVersion)))
+		err := downloadRelease(rootPath, binUrl, i.(version.Version), c, keyring, opts)
+		mu.Lock()
+		defer mu.Unlock()
+		var notFound errorNotFound
+		if errors.As(err, &notFound) {
+			slog.Info(fmt.Sprintf("release unavailable: %s: %v\n", i.(version.Version), err))
 		} else if err != nil {
 			e := fmt.Errorf("error downloading release: %w", err)
 			errs = append(errs, e)
 		} else {
 			slog.Info(fmt.Sprintf("downloaded version %s\n", i))
-			downloadedVersions = append(downloadedVersions, i.(releaseVersion))
+			downloadedVersions = append(downloadedVersions, i.(version.Version))
 		}
 	})
 	if err != nil {
@@ -168,7 +200,25 @@ func Get(rootPath, binUrl, gitUrl string) error {
 		return fmt.Errorf("errors downloading releases: %w", joined)
 	}
 
-	return DownloadGitRpcs(gitUrl, downloadedVersions)
+	return DownloadGitRpcs(gitUrl, c, downloadedVersions)
+}
+
+// hrefs returns the link targets of an html page
+func hrefs(page []byte) []string {
+	var links []string
+	doc := html.NewTokenizer(bytes.NewReader(page))
+	for tokenType := doc.Next(); tokenType != html.ErrorToken; tokenType = doc.Next() {
+		token := doc.Token()
+		if tokenType != html.StartTagToken || token.Data != "a" {
+			continue
+		}
+		for _, attr := range token.Attr {
+			if attr.Key == "href" {
+				links = append(links, attr.Val)
+			}
+		}
+	}
+	return links
 }
 
 type errorNotFound struct {
@@ -179,36 +229,103 @@ func (e errorNotFound) Error() string {
 	return "not found: " + e.release
 }
 
-var bitcoindPathRe = regexp.MustCompile(`^bitcoin-\d+\.\d+(?:\.\d+)?/bin/bitcoind$`)
+// binaryPathRe matches bitcoind and the tools documented alongside it
+var binaryPathRe = regexp.MustCompile(`^bitcoin-[^/]+/(bin|libexec)/(bitcoind|bitcoin-cli|bitcoin-wallet|bitcoin-tx|bitcoin-util)$`)
+
+// Platforms are the triples of the Linux releases
+var Platforms = []string{
+	"x86_64-linux-gnu",
+	"aarch64-linux-gnu",
+	"arm-linux-gnueabihf",
+	"riscv64-linux-gnu",
+	"powerpc64le-linux-gnu",
+}
 
-func downloadRelease(rootPath, binUrl string, version releaseVersion) error {
-	plat := "x86_64-linux-gnu"
-	if runtime.GOARCH == "arm64" {
-		plat = "aarch64-linux-gnu"
+// DefaultPlatform is the triple of the release that runs natively
+func DefaultPlatform() (string, error) {
+	switch runtime.GOARCH {
+	case "amd64":
+		return "x86_64-linux-gnu", nil
+	case "arm64":
+		return "aarch64-linux-gnu", nil
+	case "arm":
+		return "arm-linux-gnueabihf", nil
+	case "riscv64":
+		return "riscv64-linux-gnu", nil
+	case "ppc64le":
+		return "powerpc64le-linux-gnu", nil
 	}
-	releaseUrl := fmt.Sprintf("%sbitcoin-core-%s/bitcoin-%s-%s.tar.gz", binUrl, version, version, plat)
+	return "", fmt.Errorf("no release for %s, choose a platform to run under emulation", runtime.GOARCH)
+}
+
+func downloadRelease(rootPath, binUrl string, v version.Version, c cache, keyring openpgp.KeyRing, opts Options) error {
+	releaseUrl := binUrl + releaseDir(v)
+	fileName := v.Tarball(opts.Platform)
+
+	sums, err := getSums(releaseUrl, v, c, keyring, opts)
+	if err != nil {
+		return fmt.Errorf("error getting SHA256SUMS of %s: %w", v, err)
+	}
+	expected, err := sumOf(sums, fileName)
+	if err != nil {
+		return err
+	}
+
+	tarball, err := c.tarball(releaseUrl+fileName, expected)
+	if err != nil {
+		return err
+	}
+	defer silentClose(tarball)
+	return extract(filepath.Join(rootPath, "bitcoin-"+v.String(), "bin"), tarball)
+}
 
-	r, err := http.Get(releaseUrl)
+// download writes the body of url to w, and returns its hex SHA256
+func download(url string, w io.Writer) (string, error) {
+	r, err := httpGet(url)
 	if err != nil {
-		return fmt.Errorf("error downloading release: %w", err)
+		return "", err
 	}
-	defer silentClose(r.Body)
+	defer silentClose(r)
 
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(w, h), r)
+	if err != nil {
+		return "", fmt.Errorf("error downloading %s: %w", url, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func httpGet(url string) (io.ReadCloser, error) {
+	r, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading %s: %w", url, err)
+	}
 	if r.StatusCode == http.StatusNotFound {
-		return errorNotFound{release: releaseUrl}
+		silentClose(r.Body)
+		return nil, errorNotFound{release: url}
 	}
 	if r.StatusCode != http.StatusOK {
-		return fmt.Errorf("error downloading release from %s, HTTP request failed with status: %d", releaseUrl, r.StatusCode)
+		silentClose(r.Body)
+		return nil, fmt.Errorf("error downloading %s, HTTP request failed with status: %d", url, r.StatusCode)
 	}
+	return r.Body, nil
+}
 
-	gzReader, err := gzip.NewReader(r.Body)
+// extract writes the binaries of a release tarball into binDir. Binaries are under bin/ in a release,
+// and some moved to libexec/ in v30: those in bin/ win.
+func extract(binDir string, r io.Reader) error {
+	gzReader, err := gzip.NewReader(r)
 	if err != nil {
 		return err
 	}
 	defer silentClose(gzReader)
 
-	tarReader := tar.NewReader(gzReader)
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		return fmt.Errorf("error creating directory %s: %w", binDir, err)
+	}
 
+	tarReader := tar.NewReader(gzReader)
+	fromBin := make(map[string]bool)
 	for {
 		header, err := tarReader.Next()
 		if err == io.EOF {
@@ -218,24 +335,19 @@ func downloadRelease(rootPath, binUrl string, version releaseVersion) error {
 			return fmt.Errorf("error reading tar file: %w", err)
 		}
 
-		if !bitcoindPathRe.MatchString(header.Name) {
+		matches := binaryPathRe.FindStringSubmatch(strings.TrimPrefix(header.Name, "./"))
+		if matches == nil || header.Typeflag != tar.TypeReg {
 			continue
 		}
-
-		slog.Info("uncompressing " + header.Name)
-
-		filePath := fmt.Sprintf("%s/%s", rootPath, header.Name)
-		dirPath := filePath
-		if dirPath[len(dirPath)-1] != '/' {
-			dirPath = filepath.Dir(filePath)
-		}
-		if err := os.MkdirAll(dirPath, 0755); err != nil {
-			return fmt.Errorf("error creating directories for file %s: %w", filePath, err)
-		}
-		if dirPath == filePath {
+		dir, name := matches[1], matches[2]
+		if dir == "libexec" && fromBin[name] {
 			continue
 		}
+		fromBin[name] = dir == "bin"
+
+		slog.Info("uncompressing " + header.Name)
 
+		filePath := filepath.Join(binDir, name)
 		file, err := os.Create(filePath)
 		if err != nil {
 			return fmt.Errorf("error creating file %s: %w", filePath, err)
@@ -257,6 +369,10 @@ func downloadRelease(rootPath, binUrl string, version releaseVersion) error {
 		}
 	}
 
+	// e.g. the layout of the tarball changed again
+	if _, ok := fromBin["bitcoind"]; !ok {
+		return fmt.Errorf("no bitcoind in the tarball")
+	}
 	return nil
 }
 
@@ -0,0 +1,72 @@
+package downloader
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func tarball(t *testing.T, files map[string]string) *bytes.Buffer {
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return &b
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected map[string]string
+	}{
+		{"bin", map[string]string{
+			"bitcoin-27.0rc1/bin/bitcoind":    "bitcoind",
+			"bitcoin-27.0rc1/bin/bitcoin-cli": "bitcoin-cli",
+			"bitcoin-27.0rc1/bin/bitcoin-qt":  "bitcoin-qt",
+			"bitcoin-27.0rc1/README.md":       "readme",
+		}, map[string]string{"bitcoind": "bitcoind", "bitcoin-cli": "bitcoin-cli"}},
+		{"libexec", map[string]string{
+			"./bitcoin-30.0/bin/bitcoin":          "bitcoin",
+			"./bitcoin-30.0/bin/bitcoind":         "bitcoind",
+			"./bitcoin-30.0/libexec/bitcoind":     "libexec bitcoind",
+			"./bitcoin-30.0/libexec/bitcoin-util": "bitcoin-util",
+		}, map[string]string{"bitcoind": "bitcoind", "bitcoin-util": "bitcoin-util"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			binDir := filepath.Join(t.TempDir(), "bin")
+			require.NoError(t, extract(binDir, tarball(t, test.files)))
+
+			entries, err := os.ReadDir(binDir)
+			require.NoError(t, err)
+			extracted := make(map[string]string, len(entries))
+			for _, e := range entries {
+				b, err := os.ReadFile(filepath.Join(binDir, e.Name()))
+				require.NoError(t, err)
+				extracted[e.Name()] = string(b)
+				info, err := e.Info()
+				require.NoError(t, err)
+				assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+			}
+			assert.Equal(t, test.expected, extracted)
+		})
+	}
+}
+
+func TestExtractWithoutBitcoind(t *testing.T) {
+	files := map[string]string{"bitcoin-30.0/share/bitcoind": "bitcoind", "bitcoin-30.0/bin/bitcoin-cli": "bitcoin-cli"}
+	err := extract(filepath.Join(t.TempDir(), "bin"), tarball(t, files))
+	assert.ErrorContains(t, err, "no bitcoind")
+}
@@ -1,51 +1,58 @@
 package downloader
 
 import (
+	"bitcoinrpcschema/internal/version"
 	"errors"
 	"fmt"
-	"github.com/go-git/go-billy/v5/memfs"
 	"github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/storage/memory"
-	"io"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"log/slog"
 	"os"
 	"path"
+	"strings"
 )
 
-func DownloadGitRpcs(repoUrl string, versions []releaseVersion) error {
-	rpcs, err := getGitRpcs(repoUrl, versions)
+func DownloadGitRpcs(repoUrl string, c cache, versions []version.Version) error {
+	rpcs, err := getGitRpcs(repoUrl, c, versions)
 	if err != nil {
 		e := fmt.Errorf("failed to get rpcs from git repo %s: %w", repoUrl, err)
 		return e
 	}
 	for v, files := range rpcs {
-		dir := path.Join("bitcoin-core", "bitcoin-"+v.String())
-		for p, content := range files {
-			fullPath := path.Join(dir, p)
-			err := os.WriteFile(fullPath, content, 0644)
-			if err != nil {
-				e := fmt.Errorf("failed to write bitcoin rpc file %s: %w", fullPath, err)
-				return e
-			}
+		err := writeRpcFiles(path.Join("bitcoin-core", "bitcoin-"+v.String()), files)
+		if err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-func getGitRpcs(repoUrl string, versions []releaseVersion) (map[releaseVersion]map[string][]byte, error) {
-	co := git.CloneOptions{
-		Progress: os.Stderr,
-		URL:      repoUrl,
-	}
-	fs := memfs.New()
-	slog.Info("cloning bitcoin repo: " + repoUrl)
-	r, err := git.Clone(memory.NewStorage(), fs, &co)
+func writeRpcFiles(dir string, files map[string][]byte) error {
+	for p, content := range files {
+		fullPath := path.Join(dir, p)
+		err := os.MkdirAll(path.Dir(fullPath), 0755)
+		if err != nil {
+			e := fmt.Errorf("failed to create directory for %s: %w", fullPath, err)
+			return e
+		}
+		err = os.WriteFile(fullPath, content, 0644)
+		if err != nil {
+			e := fmt.Errorf("failed to write bitcoin rpc file %s: %w", fullPath, err)
+			return e
+		}
+	}
+	return nil
+}
+
+func getGitRpcs(repoUrl string, c cache, versions []version.Version) (map[version.Version]map[string][]byte, error) {
+	r, err := mirror(repoUrl, c)
 	if err != nil {
-		e := fmt.Errorf("failed to clone bitcoin repo: %w", err)
-		return nil, e
+		return nil, err
 	}
 
-	rpcs := make(map[releaseVersion]map[string][]byte, len(versions))
+	rpcs := make(map[version.Version]map[string][]byte, len(versions))
 	for _, v := range versions {
 		rpcs[v], err = getVersionRpcCppFiles(r, v)
 		if err != nil {
@@ -56,60 +63,126 @@ func getGitRpcs(repoUrl string, versions []releaseVersion) (map[releaseVersion]m
 	return rpcs, nil
 }
 
-func getVersionRpcCppFiles(r *git.Repository, v releaseVersion) (map[string][]byte, error) {
-	tagName := "v" + v.String()
-	t, err := r.Tag(tagName)
+// mirror opens the bare mirror of the repo in the cache, creating it if needed, and fetches new tags unless offline
+func mirror(repoUrl string, c cache) (*git.Repository, error) {
+	r, err := git.PlainOpen(c.gitDir())
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		if c.offline {
+			e := fmt.Errorf("offline and no mirror of the bitcoin repo in %s", c.gitDir())
+			return nil, e
+		}
+		r, err = git.PlainInit(c.gitDir(), true)
+		if err != nil {
+			e := fmt.Errorf("failed to create bitcoin repo mirror: %w", err)
+			return nil, e
+		}
+		_, err = r.CreateRemote(&config.RemoteConfig{
+			Name:  git.DefaultRemoteName,
+			URLs:  []string{repoUrl},
+			Fetch: []config.RefSpec{"+refs/tags/*:refs/tags/*"},
+		})
+	}
+	if err != nil {
+		e := fmt.Errorf("failed to open bitcoin repo mirror: %w", err)
+		return nil, e
+	}
+	if c.offline {
+		return r, nil
+	}
+
+	slog.Info("fetching bitcoin repo: " + repoUrl)
+	err = r.Fetch(&git.FetchOptions{
+		RemoteURL: repoUrl,
+		Progress:  os.Stderr,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		e := fmt.Errorf("failed to fetch bitcoin repo: %w", err)
+		return nil, e
+	}
+	return r, nil
+}
+
+func getVersionRpcCppFiles(r *git.Repository, v version.Version) (map[string][]byte, error) {
+	h, err := r.ResolveRevision(plumbing.Revision("refs/tags/" + v.Tag()))
 	if err != nil {
 		e := fmt.Errorf("failed to get tag: %w", err)
 		return nil, e
 	}
-	w, err := r.Worktree()
+	commit, err := r.CommitObject(*h)
 	if err != nil {
-		e := fmt.Errorf("failed to get worktree: %w", err)
+		e := fmt.Errorf("failed to get commit of tag: %w", err)
 		return nil, e
 	}
-	err = w.Checkout(&git.CheckoutOptions{Branch: t.Name()})
+	tree, err := commit.Tree()
 	if err != nil {
-		e := fmt.Errorf("failed to check out: %w", err)
+		e := fmt.Errorf("failed to get tree of tag: %w", err)
 		return nil, e
 	}
+	return getTreeRpcCppFiles(tree)
+}
 
-	wantedPaths := []string{"src/rpc/register.h"}
-	ps, err := w.Filesystem.ReadDir("src/rpc")
+// getTreeRpcCppFiles reads the sources describing RPCs and other interfaces from a source tree
+func getTreeRpcCppFiles(tree *object.Tree) (map[string][]byte, error) {
+	// wanted source paths, by where they are written in the release directory
+	wantedPaths := map[string]string{
+		"register.h":                 "src/rpc/register.h",
+		"rest/rest.cpp":              "src/rest.cpp",
+		"zmq/zmqpublishnotifier.cpp": "src/zmq/zmqpublishnotifier.cpp",
+	}
+	rpcCpps, err := cppFiles(tree, "src/rpc", "")
 	if err != nil {
-		e := fmt.Errorf("failed to read dir: %w", err)
-		return nil, e
+		return nil, err
 	}
-	for _, p := range ps {
-		if p.IsDir() {
-			continue
-		}
-		if path.Ext(p.Name()) != ".cpp" {
-			continue
-		}
-		wantedPaths = append(wantedPaths, "src/rpc/"+p.Name())
+	for _, p := range rpcCpps {
+		wantedPaths[path.Base(p)] = p
+	}
+
+	// wallet RPCs moved from src/wallet/rpc*.cpp to src/wallet/rpc/ in v23
+	walletCpps, err := cppFiles(tree, "src/wallet/rpc", "")
+	if errors.Is(err, object.ErrDirectoryNotFound) {
+		walletCpps, err = cppFiles(tree, "src/wallet", "rpc")
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range walletCpps {
+		wantedPaths[path.Join("wallet", path.Base(p))] = p
 	}
 
 	files := make(map[string][]byte, len(wantedPaths))
-	for _, p := range wantedPaths {
-		f, err := w.Filesystem.Open(p)
+	for fileName, p := range wantedPaths {
+		f, err := tree.File(p)
 		if err != nil {
-			e := fmt.Errorf("failed to open file: %w", err)
+			e := fmt.Errorf("failed to open file %s: %w", p, err)
 			return nil, e
 		}
-		content, err := io.ReadAll(f)
-		closeErr := f.Close()
+		content, err := f.Contents()
 		if err != nil {
-			e := fmt.Errorf("failed to read file: %w", err)
-			return nil, errors.Join(e, closeErr)
-		}
-		if closeErr != nil {
-			e := fmt.Errorf("failed to close file: %w", closeErr)
+			e := fmt.Errorf("failed to read file %s: %w", p, err)
 			return nil, e
 		}
 
-		fileName := path.Base(p)
-		files[fileName] = content
+		files[fileName] = []byte(content)
+	}
+	return files, nil
+}
+
+// cppFiles lists the .cpp files directly in dir whose names start with prefix
+func cppFiles(tree *object.Tree, dir, prefix string) ([]string, error) {
+	t, err := tree.Tree(dir)
+	if err != nil {
+		e := fmt.Errorf("failed to read dir %s: %w", dir, err)
+		return nil, e
+	}
+	var files []string
+	for _, entry := range t.Entries {
+		if !entry.Mode.IsFile() {
+			continue
+		}
+		if path.Ext(entry.Name) != ".cpp" || !strings.HasPrefix(entry.Name, prefix) {
+			continue
+		}
+		files = append(files, path.Join(dir, entry.Name))
 	}
 	return files, nil
 }
@@ -0,0 +1,86 @@
+package downloader
+
+import (
+	"bitcoinrpcschema/internal/version"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestRepo creates a repo with the layout of bitcoin v22, where wallet RPCs are in src/wallet/rpc*.cpp
+func newTestRepo(t *testing.T) (string, *git.Repository) {
+	dir := t.TempDir()
+	r, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+	files := map[string]string{
+		"src/rpc/register.h":             "register",
+		"src/rpc/blockchain.cpp":         "blockchain",
+		"src/rpc/util.h":                 "util",
+		"src/rest.cpp":                   "rest",
+		"src/zmq/zmqpublishnotifier.cpp": "zmq",
+		"src/wallet/rpcwallet.cpp":       "rpcwallet",
+		"src/wallet/wallet.cpp":          "wallet",
+	}
+	w, err := r.Worktree()
+	require.NoError(t, err)
+	for p, content := range files {
+		full := filepath.Join(dir, p)
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0755))
+		require.NoError(t, os.WriteFile(full, []byte(content), 0644))
+		_, err = w.Add(p)
+		require.NoError(t, err)
+	}
+	sig := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+	h, err := w.Commit("v22.0", &git.CommitOptions{Author: sig})
+	require.NoError(t, err)
+	_, err = r.CreateTag("v22.0", h, &git.CreateTagOptions{Tagger: sig, Message: "v22.0"})
+	require.NoError(t, err)
+	return dir, r
+}
+
+func TestMirror(t *testing.T) {
+	repoDir, _ := newTestRepo(t)
+	c := cache{dir: t.TempDir()}
+
+	_, err := mirror(repoDir, cache{dir: c.dir, offline: true})
+	assert.Error(t, err, "no mirror to use offline yet")
+
+	r, err := mirror(repoDir, c)
+	require.NoError(t, err)
+	files, err := getVersionRpcCppFiles(r, version.Version{Major: 22})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"register.h":                 []byte("register"),
+		"blockchain.cpp":             []byte("blockchain"),
+		"rest/rest.cpp":              []byte("rest"),
+		"zmq/zmqpublishnotifier.cpp": []byte("zmq"),
+		"wallet/rpcwallet.cpp":       []byte("rpcwallet"),
+	}, files)
+
+	// fetching again is a no-op, and offline the mirror is used as is
+	_, err = mirror(repoDir, c)
+	require.NoError(t, err)
+	r, err = mirror(repoDir, cache{dir: c.dir, offline: true})
+	require.NoError(t, err)
+	_, err = getVersionRpcCppFiles(r, version.Version{Major: 22})
+	assert.NoError(t, err)
+}
+
+func TestGetVersionRpcCppFilesZeroPatch(t *testing.T) {
+	_, r := newTestRepo(t)
+	head, err := r.Head()
+	require.NoError(t, err)
+	sig := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+	_, err = r.CreateTag("v0.21.0", head.Hash(), &git.CreateTagOptions{Tagger: sig, Message: "v0.21.0"})
+	require.NoError(t, err)
+
+	// 0.x releases are tagged with their patch number, even when it is 0
+	files, err := getVersionRpcCppFiles(r, version.Version{Minor: 21})
+	require.NoError(t, err)
+	assert.Contains(t, files, "blockchain.cpp")
+}
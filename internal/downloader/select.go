@@ -0,0 +1,146 @@
+package downloader
+
+import (
+	"bitcoinrpcschema/internal/version"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Selection chooses which releases to download. A release is kept if it meets every set criterion,
+// the zero Selection keeps every release.
+type Selection struct {
+	// Versions are exact versions, like 0.21.2, 22.1 or 27.0rc1. Listing a release candidate includes it
+	// without ReleaseCandidates, and every listed version must be found.
+	Versions []string
+	// Constraint is a space separated list of bounds, like ">=22 <28". A bound only compares the parts
+	// of the version it has, so "<=27" includes 27.2.
+	Constraint string
+	// LatestMajors keeps the releases of that many of the latest major versions, all of them if 0.
+	// Major versions before 22 were 0.x.
+	LatestMajors int
+	// LatestPatch keeps only the latest release of each major version
+	LatestPatch bool
+	// ReleaseCandidates includes release candidates, from the test.rcN directories of each release
+	ReleaseCandidates bool
+}
+
+// selector is a parsed Selection
+type selector struct {
+	versions     map[version.Version]bool
+	constraint   version.Constraint
+	latestMajors int
+	latestPatch  bool
+	rcs          bool
+}
+
+func (s Selection) compile() (selector, error) {
+	sel := selector{
+		latestMajors: s.LatestMajors,
+		latestPatch:  s.LatestPatch,
+		rcs:          s.ReleaseCandidates,
+	}
+	if len(s.Versions) > 0 {
+		sel.versions = make(map[version.Version]bool, len(s.Versions))
+	}
+	for _, v := range s.Versions {
+		parsed, err := version.Parse(strings.TrimPrefix(v, "v"))
+		if err != nil {
+			return selector{}, fmt.Errorf("invalid version %q, expected e.g. 0.21.2 or 27.0rc1", v)
+		}
+		sel.versions[parsed] = true
+	}
+	var err error
+	sel.constraint, err = version.ParseConstraint(s.Constraint)
+	if err != nil {
+		return selector{}, err
+	}
+	return sel, nil
+}
+
+// listsRCs tells whether to look for the release candidates of release v
+func (s selector) listsRCs(v version.Version) bool {
+	if s.rcs {
+		return true
+	}
+	for listed := range s.versions {
+		if listed.Pre != "" {
+			listed.Pre = ""
+			if listed == v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// missing returns the exact versions that weren't chosen, sorted
+func (s selector) missing(chosen []version.Version) []version.Version {
+	var missing []version.Version
+	for listed := range s.versions {
+		if !slices.Contains(chosen, listed) {
+			missing = append(missing, listed)
+		}
+	}
+	slices.SortFunc(missing, version.Version.Cmp)
+	return missing
+}
+
+// matches tells whether v meets the per version criteria. Leniently, the exact versions
+// only have to match without their release candidate, to find in which releases to look for them.
+func (s selector) matches(v version.Version, lenient bool) bool {
+	if v.Pre != "" && !s.rcs && !s.versions[v] {
+		return false
+	}
+	if s.versions != nil {
+		found := s.versions[v]
+		if lenient {
+			for listed := range s.versions {
+				listed.Pre = ""
+				found = found || listed == v
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return s.constraint.Matches(v)
+}
+
+// choose returns the selected versions, sorted
+func (s selector) choose(versions []version.Version, lenient bool) []version.Version {
+	var chosen []version.Version
+	for _, v := range versions {
+		if s.matches(v, lenient) {
+			chosen = append(chosen, v)
+		}
+	}
+	slices.SortFunc(chosen, version.Version.Cmp)
+	chosen = slices.Compact(chosen)
+
+	if s.latestMajors > 0 {
+		var series []version.Version
+		for _, v := range chosen {
+			if len(series) == 0 || series[len(series)-1] != v.Series() {
+				series = append(series, v.Series())
+			}
+		}
+		if len(series) > s.latestMajors {
+			first := series[len(series)-s.latestMajors]
+			chosen = slices.DeleteFunc(chosen, func(v version.Version) bool {
+				return v.Series().Cmp(first) < 0
+			})
+		}
+	}
+
+	if s.latestPatch && !lenient {
+		var latest []version.Version
+		for i, v := range chosen {
+			if i+1 == len(chosen) || chosen[i+1].Series() != v.Series() {
+				latest = append(latest, v)
+			}
+		}
+		chosen = latest
+	}
+	return chosen
+}
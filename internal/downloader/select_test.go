@@ -0,0 +1,82 @@
+package downloader
+
+import (
+	"bitcoinrpcschema/internal/version"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func v(major, minor, patch, rc uint) version.Version {
+	rv := version.Version{Major: major, Minor: minor, Patch: patch}
+	if rc > 0 {
+		rv.Pre = fmt.Sprintf("rc%d", rc)
+	}
+	return rv
+}
+
+var testReleases = []version.Version{
+	v(0, 20, 1, 0), v(0, 21, 0, 0), v(0, 21, 1, 0), v(0, 21, 2, 0),
+	v(22, 0, 0, 0), v(22, 1, 0, 0), v(23, 0, 0, 0), v(27, 0, 0, 1),
+	v(27, 0, 0, 2), v(27, 0, 0, 0), v(27, 1, 0, 0), v(28, 0, 0, 1),
+}
+
+func TestSelection(t *testing.T) {
+	tests := []struct {
+		name     string
+		sel      Selection
+		expected []version.Version
+	}{
+		{"latest majors", Selection{LatestMajors: 3}, []version.Version{v(22, 0, 0, 0), v(22, 1, 0, 0), v(23, 0, 0, 0), v(27, 0, 0, 0), v(27, 1, 0, 0)}},
+		{"old majors", Selection{Constraint: "<22 >=0.21", LatestPatch: true}, []version.Version{v(0, 21, 2, 0)}},
+		{"bounds compare the parts they have", Selection{Constraint: ">=22 <=27"}, []version.Version{
+			v(22, 0, 0, 0), v(22, 1, 0, 0), v(23, 0, 0, 0), v(27, 0, 0, 0), v(27, 1, 0, 0)}},
+		{"latest patch", Selection{LatestMajors: 2, LatestPatch: true}, []version.Version{v(23, 0, 0, 0), v(27, 1, 0, 0)}},
+		{"release candidates", Selection{Constraint: ">=27", ReleaseCandidates: true}, []version.Version{
+			v(27, 0, 0, 1), v(27, 0, 0, 2), v(27, 0, 0, 0), v(27, 1, 0, 0), v(28, 0, 0, 1)}},
+		{"explicit versions", Selection{Versions: []string{"0.21.1", "v22.1", "27.0rc2", "30.0"}, ReleaseCandidates: true}, []version.Version{
+			v(0, 21, 1, 0), v(22, 1, 0, 0), v(27, 0, 0, 2)}},
+		{"listed release candidates without the others", Selection{Versions: []string{"27.0rc2", "27.0"}}, []version.Version{
+			v(27, 0, 0, 2), v(27, 0, 0, 0)}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sel, err := test.sel.compile()
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, sel.choose(testReleases, false))
+		})
+	}
+
+	sel, err := Selection{Versions: []string{"27.0rc2", "30.0"}}.compile()
+	require.NoError(t, err)
+	assert.True(t, sel.listsRCs(v(27, 0, 0, 0)))
+	assert.False(t, sel.listsRCs(v(27, 1, 0, 0)))
+	assert.Equal(t, []version.Version{v(30, 0, 0, 0)}, sel.missing(sel.choose(testReleases, false)))
+
+	_, err = Selection{Constraint: "~22"}.compile()
+	assert.Error(t, err)
+	_, err = Selection{Versions: []string{"22"}}.compile()
+	assert.Error(t, err)
+}
+
+func TestReleaseDir(t *testing.T) {
+	assert.Equal(t, "bitcoin-core-0.21.2/", releaseDir(v(0, 21, 2, 0)))
+	assert.Equal(t, "bitcoin-core-0.21.0/", releaseDir(v(0, 21, 0, 0)))
+	assert.Equal(t, "bitcoin-core-0.21.0/test.rc1/", releaseDir(v(0, 21, 0, 1)))
+	assert.Equal(t, "bitcoin-core-27.0/test.rc1/", releaseDir(v(27, 0, 0, 1)))
+}
+
+func TestParseReleaseVersion(t *testing.T) {
+	rv, err := parseReleaseVersion("bitcoin-core-0.21.2/")
+	require.NoError(t, err)
+	assert.Equal(t, v(0, 21, 2, 0), rv)
+	rv, err = parseReleaseVersion("bitcoin-core-27.0/")
+	require.NoError(t, err)
+	assert.Equal(t, v(27, 0, 0, 0), rv)
+
+	for _, invalid := range []string{"bitcoin-core-27.0rc1/", "bitcoin-core-27/", "test.rc1/", "../"} {
+		_, err = parseReleaseVersion(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
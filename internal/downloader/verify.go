@@ -0,0 +1,193 @@
+package downloader
+
+import (
+	"bitcoinrpcschema/internal/version"
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/clearsign"
+	pgperrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const armorEnd = "-----END PGP "
+
+// armoredBlocks splits concatenated armored blocks, like the signatures of all builders in SHA256SUMS.asc
+func armoredBlocks(data []byte, blockType string) [][]byte {
+	begin := []byte("-----BEGIN PGP " + blockType + "-----")
+	var blocks [][]byte
+	for {
+		start := bytes.Index(data, begin)
+		if start < 0 {
+			return blocks
+		}
+		data = data[start:]
+		end := bytes.Index(data, []byte(armorEnd))
+		if end < 0 {
+			return blocks
+		}
+		lineEnd := bytes.IndexByte(data[end:], '\n')
+		if lineEnd < 0 {
+			lineEnd = len(data) - end
+		}
+		blocks = append(blocks, data[:end+lineEnd])
+		data = data[end+lineEnd:]
+	}
+}
+
+// readKeyring reads the armored public keys of a file, or of every file in a directory
+func readKeyring(p string) (openpgp.EntityList, error) {
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{p}
+	if info.IsDir() {
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return nil, err
+		}
+		files = files[:0]
+		for _, e := range entries {
+			if !e.IsDir() {
+				files = append(files, filepath.Join(p, e.Name()))
+			}
+		}
+	}
+
+	var keyring openpgp.EntityList
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		for _, block := range armoredBlocks(data, "PUBLIC KEY BLOCK") {
+			keys, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(block))
+			if err != nil {
+				e := fmt.Errorf("failed to read key in %s: %w", f, err)
+				return nil, e
+			}
+			keyring = append(keyring, keys...)
+		}
+	}
+	if len(keyring) == 0 {
+		return nil, fmt.Errorf("no keys in %s", p)
+	}
+	return keyring, nil
+}
+
+// verifySums checks that enough keys of the keyring signed the sums, and returns the signed sums.
+// Before v22 SHA256SUMS.asc of release v was a clearsigned copy of the sums, with a single signature that must
+// be from the keyring. Since then it holds detached signatures from every builder, minSignatures of which
+// must be from the keyring, and a clearsigned copy is refused so it can't stand in for them.
+func verifySums(keyring openpgp.KeyRing, v version.Version, sums, asc []byte, minSignatures int) ([]byte, error) {
+	signers := make(map[uint64]bool)
+	addSigner := func(signer *openpgp.Entity, err error) {
+		if errors.Is(err, pgperrors.ErrUnknownIssuer) {
+			return
+		}
+		if err != nil {
+			slog.Warn(fmt.Sprintf("ignoring signature that failed to verify: %v", err))
+			return
+		}
+		signers[signer.PrimaryKey.KeyId] = true
+	}
+
+	if block, _ := clearsign.Decode(asc); block != nil {
+		if !clearsignedSums(v) {
+			return nil, fmt.Errorf("SHA256SUMS.asc of %s is clearsigned, expected detached signatures since v22", v)
+		}
+		addSigner(block.VerifySignature(keyring, nil))
+		sums = block.Plaintext
+		minSignatures = min(minSignatures, 1)
+	} else {
+		for _, sig := range armoredBlocks(asc, "SIGNATURE") {
+			addSigner(openpgp.CheckArmoredDetachedSignature(keyring, bytes.NewReader(sums), bytes.NewReader(sig), nil))
+		}
+	}
+	if len(signers) < minSignatures {
+		return nil, fmt.Errorf("SHA256SUMS has %d valid signatures from the keyring, need %d", len(signers), minSignatures)
+	}
+	return sums, nil
+}
+
+// clearsignedSums is whether release v published its SHA256SUMS clearsigned, with a single signature
+func clearsignedSums(v version.Version) bool {
+	return v.Cmp(version.Version{Major: 22}) < 0
+}
+
+// getSums downloads the SHA256SUMS of release v, verifying its signatures unless they are skipped
+func getSums(releaseUrl string, v version.Version, c cache, keyring openpgp.KeyRing, opts Options) ([]byte, error) {
+	sums, err := c.get(releaseUrl+"SHA256SUMS", false)
+	var notFound errorNotFound
+	if err != nil && !errors.As(err, &notFound) {
+		return nil, err
+	}
+	ascUrl := releaseUrl + "SHA256SUMS.asc"
+	ascCached := c.cached(ascUrl)
+	asc, ascErr := c.get(ascUrl, false)
+
+	if opts.SkipSignatures {
+		if sums != nil {
+			return sums, nil
+		}
+		// only the clearsigned SHA256SUMS.asc exists
+		if ascErr != nil {
+			return nil, ascErr
+		}
+		block, _ := clearsign.Decode(asc)
+		if block == nil {
+			return nil, fmt.Errorf("no SHA256SUMS for %s", releaseUrl)
+		}
+		return block.Plaintext, nil
+	}
+	if ascErr != nil {
+		return nil, ascErr
+	}
+	verified, err := verifySums(keyring, v, sums, asc, opts.MinSignatures)
+	if err == nil || !ascCached || c.offline {
+		return verified, err
+	}
+	// builders add their signatures after a release, so the cached copy may predate enough of them
+	slog.Info(fmt.Sprintf("refreshing %s: %v", ascUrl, err))
+	asc, err = c.get(ascUrl, true)
+	if err != nil {
+		return nil, err
+	}
+	return verifySums(keyring, v, sums, asc, opts.MinSignatures)
+}
+
+// sumOf finds the hash of a file in SHA256SUMS
+func sumOf(sums []byte, fileName string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		sum, name, ok := strings.Cut(scanner.Text(), "  ")
+		if ok && strings.TrimPrefix(name, "*") == fileName {
+			return sum, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	// e.g. the release wasn't built for this platform
+	return "", errorNotFound{release: fileName}
+}
+
+func getBody(url string) ([]byte, error) {
+	r, err := httpGet(url)
+	if err != nil {
+		return nil, err
+	}
+	defer silentClose(r)
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", url, err)
+	}
+	return b, nil
+}
@@ -0,0 +1,128 @@
+package downloader
+
+import (
+	"bitcoinrpcschema/internal/version"
+	"bytes"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/clearsign"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+var release27 = version.Version{Major: 27}
+
+const testSums = "0123abcd  bitcoin-27.0-x86_64-linux-gnu.tar.gz\n4567ef01  bitcoin-27.0-aarch64-linux-gnu.tar.gz\n"
+
+func newBuilder(t *testing.T, name string) *openpgp.Entity {
+	e, err := openpgp.NewEntity(name, "", name+"@example.com", nil)
+	require.NoError(t, err)
+	return e
+}
+
+func detachSign(t *testing.T, signer *openpgp.Entity, data string) []byte {
+	var b bytes.Buffer
+	require.NoError(t, openpgp.ArmoredDetachSign(&b, signer, bytes.NewReader([]byte(data)), nil))
+	b.WriteString("\n")
+	return b.Bytes()
+}
+
+func TestVerifySums(t *testing.T) {
+	alice, bob, mallory := newBuilder(t, "alice"), newBuilder(t, "bob"), newBuilder(t, "mallory")
+	keyring := openpgp.EntityList{alice, bob}
+
+	// signatures from unknown keys and over other data don't count
+	var asc []byte
+	asc = append(asc, detachSign(t, alice, testSums)...)
+	asc = append(asc, detachSign(t, mallory, testSums)...)
+	asc = append(asc, detachSign(t, bob, "tampered")...)
+	asc = append(asc, detachSign(t, alice, testSums)...)
+
+	sums, err := verifySums(keyring, release27, []byte(testSums), asc, 1)
+	require.NoError(t, err)
+	assert.Equal(t, testSums, string(sums))
+
+	_, err = verifySums(keyring, release27, []byte(testSums), asc, 2)
+	assert.ErrorContains(t, err, "has 1 valid signatures from the keyring, need 2")
+
+	asc = append(asc, detachSign(t, bob, testSums)...)
+	_, err = verifySums(keyring, release27, []byte(testSums), asc, 2)
+	assert.NoError(t, err)
+}
+
+func TestVerifyClearsignedSums(t *testing.T) {
+	alice, mallory := newBuilder(t, "alice"), newBuilder(t, "mallory")
+	keyring := openpgp.EntityList{alice}
+	clearsigned := func(signer *openpgp.Entity) []byte {
+		var b bytes.Buffer
+		w, err := clearsign.Encode(&b, signer.PrivateKey, nil)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(testSums))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		return b.Bytes()
+	}
+
+	// before v22 the only signature is enough, whatever the minimum for detached signatures
+	release21 := version.Version{Minor: 21, Patch: 2}
+	sums, err := verifySums(keyring, release21, nil, clearsigned(alice), 3)
+	require.NoError(t, err)
+	assert.Equal(t, testSums, string(sums))
+
+	_, err = verifySums(keyring, release21, nil, clearsigned(mallory), 3)
+	assert.ErrorContains(t, err, "has 0 valid signatures from the keyring, need 1")
+
+	// since, a single clearsigned signature doesn't count for the builders' detached signatures
+	_, err = verifySums(keyring, release27, nil, clearsigned(alice), 3)
+	assert.ErrorContains(t, err, "SHA256SUMS.asc of 27.0 is clearsigned")
+}
+
+func TestGetSumsRefreshesSignatures(t *testing.T) {
+	alice, bob := newBuilder(t, "alice"), newBuilder(t, "bob")
+	keyring := openpgp.EntityList{alice, bob}
+	asc := detachSign(t, alice, testSums)
+	var ascRequests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bin/bitcoin-core-27.0/SHA256SUMS":
+			_, _ = w.Write([]byte(testSums))
+		case "/bin/bitcoin-core-27.0/SHA256SUMS.asc":
+			ascRequests.Add(1)
+			_, _ = w.Write(asc)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	releaseUrl := srv.URL + "/bin/bitcoin-core-27.0/"
+	c := cache{dir: t.TempDir()}
+
+	_, err := getSums(releaseUrl, release27, c, keyring, Options{MinSignatures: 1})
+	require.NoError(t, err)
+	_, err = getSums(releaseUrl, release27, c, keyring, Options{MinSignatures: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), ascRequests.Load())
+
+	// bob signed since, but the cached copy only has alice's signature
+	asc = append(asc, detachSign(t, bob, testSums)...)
+	offline := cache{dir: c.dir, offline: true}
+	_, err = getSums(releaseUrl, release27, offline, keyring, Options{MinSignatures: 2})
+	assert.ErrorContains(t, err, "need 2")
+	_, err = getSums(releaseUrl, release27, c, keyring, Options{MinSignatures: 2})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), ascRequests.Load())
+	_, err = getSums(releaseUrl, release27, offline, keyring, Options{MinSignatures: 2})
+	assert.NoError(t, err)
+}
+
+func TestSumOf(t *testing.T) {
+	sum, err := sumOf([]byte(testSums), "bitcoin-27.0-aarch64-linux-gnu.tar.gz")
+	require.NoError(t, err)
+	assert.Equal(t, "4567ef01", sum)
+
+	_, err = sumOf([]byte(testSums), "bitcoin-27.0-riscv64-linux-gnu.tar.gz")
+	assert.Error(t, err)
+}
@@ -0,0 +1,123 @@
+package gensite
+
+import (
+	"bitcoinrpcschema/internal/bitcoind"
+	coreversion "bitcoinrpcschema/internal/version"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// The JSON API mirrors the HTML site for tools. Its shape is independent of the page templates,
+// so changes to the HTML don't break consumers.
+
+type apiVersions struct {
+	Latest   string   `json:"latest"`
+	Versions []string `json:"versions"`
+}
+
+type apiCommandSummary struct {
+	Name    string `json:"name"`
+	Section string `json:"section"`
+	Url     string `json:"url"`
+}
+
+type apiCommand struct {
+	Name           string              `json:"name"`
+	Version        string              `json:"version"`
+	Section        string              `json:"section"`
+	Help           string              `json:"help"`
+	Usage          string              `json:"usage"`
+	Explanation    []string            `json:"explanation"`
+	Arguments      []bitcoind.Argument `json:"arguments"`
+	Results        []bitcoind.Result   `json:"results"`
+	RequiresWallet bool                `json:"requiresWallet"`
+	Availability   *apiAvailability    `json:"availability,omitempty"`
+}
+
+type apiAvailability struct {
+	First     string     `json:"first"`
+	Last      string     `json:"last"`
+	RemovedIn string     `json:"removedIn,omitempty"`
+	Ranges    []apiRange `json:"ranges"`
+}
+
+// apiRange is a run of consecutive releases a command appears in
+type apiRange struct {
+	First     string `json:"first"`
+	Last      string `json:"last"`
+	RemovedIn string `json:"removedIn,omitempty"`
+}
+
+func apiVersionPath(rv coreversion.Version) string {
+	return "api/" + rv.String()
+}
+
+// addApi adds the JSON API documents for a single version
+func addApi(site site, rv coreversion.Version, sections map[string][]bitcoind.Command, avail map[string]*availability) error {
+	summaries := make([]apiCommandSummary, 0)
+	for sec, cmds := range sections {
+		for _, cmd := range cmds {
+			p := fmt.Sprintf("%s/%s.json", apiVersionPath(rv), cmd.Name)
+			summaries = append(summaries, apiCommandSummary{Name: cmd.Name, Section: sec, Url: "/" + p})
+
+			c, err := newApiCommand(rv, sec, cmd, avail[cmd.Name])
+			if err != nil {
+				return fmt.Errorf("failed to describe command %s: %w", cmd.Name, err)
+			}
+			err = site.addJson(p, c)
+			if err != nil {
+				return fmt.Errorf("failed to add command %s: %w", cmd.Name, err)
+			}
+		}
+	}
+	slices.SortFunc(summaries, func(a, b apiCommandSummary) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return site.addJson(apiVersionPath(rv)+"/commands.json", summaries)
+}
+
+func newApiCommand(rv coreversion.Version, section string, cmd bitcoind.Command, avail *availability) (*apiCommand, error) {
+	desc, err := parseDescription(cmd.Help)
+	if err != nil {
+		e := fmt.Errorf("failed to parse description: %w", err)
+		return nil, e
+	}
+	c := &apiCommand{
+		Name:           cmd.Name,
+		Version:        rv.String(),
+		Section:        section,
+		Help:           cmd.Help,
+		Usage:          desc.Usage,
+		Explanation:    desc.Explanation,
+		Arguments:      cmd.Arguments,
+		Results:        cmd.Results,
+		RequiresWallet: cmd.RequiresWallet,
+	}
+	if c.Explanation == nil {
+		c.Explanation = []string{}
+	}
+	if c.Arguments == nil {
+		c.Arguments = []bitcoind.Argument{}
+	}
+	if c.Results == nil {
+		c.Results = []bitcoind.Result{}
+	}
+	if avail != nil {
+		c.Availability = &apiAvailability{First: avail.First(), Last: avail.Last(), RemovedIn: avail.RemovedIn()}
+		for _, r := range avail.Ranges {
+			c.Availability.Ranges = append(c.Availability.Ranges, apiRange(r))
+		}
+	}
+	return c, nil
+}
+
+func (s site) addJson(path string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	s.addRaw(path, b)
+	return nil
+}
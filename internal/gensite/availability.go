@@ -0,0 +1,115 @@
+package gensite
+
+import (
+	"bitcoinrpcschema/internal/bitcoind"
+	coreversion "bitcoinrpcschema/internal/version"
+	_ "embed"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+//go:embed commands.html
+var commandsHtml string
+
+var commandsTmpl = mustBtcTemplate("commands", commandsHtml)
+
+// availability records which releases a command appears in
+type availability struct {
+	Name string
+	// Ranges are the runs of consecutive releases the command appears in, oldest first.
+	// A command that was removed and added back has several.
+	Ranges      []versionRange
+	LastSection string
+	Sections    []string
+	// last is the index of the last release the command appears in
+	last int
+}
+
+// versionRange is a run of consecutive releases, RemovedIn is the release after it, if any
+type versionRange struct {
+	First     string
+	Last      string
+	RemovedIn string
+}
+
+// add records that the command appears in versions[i], the releases being visited in ascending order
+func (a *availability) add(versions []coreversion.Version, i int) {
+	if len(a.Ranges) > 0 && a.last == i {
+		return
+	}
+	if len(a.Ranges) == 0 || a.last != i-1 {
+		a.Ranges = append(a.Ranges, versionRange{First: versions[i].String()})
+	}
+	r := &a.Ranges[len(a.Ranges)-1]
+	r.Last = versions[i].String()
+	r.RemovedIn = ""
+	if i+1 < len(versions) {
+		r.RemovedIn = versions[i+1].String()
+	}
+	a.last = i
+}
+
+// First is the first release the command appears in
+func (a *availability) First() string {
+	return a.Ranges[0].First
+}
+
+// Last is the last release the command appears in
+func (a *availability) Last() string {
+	return a.Ranges[len(a.Ranges)-1].Last
+}
+
+// RemovedIn is the release the command was last removed in, empty if the latest release has it
+func (a *availability) RemovedIn() string {
+	return a.Ranges[len(a.Ranges)-1].RemovedIn
+}
+
+// commandIndex is the page listing every command across all versions
+type commandIndex struct {
+	Latest   string
+	Commands []*availability
+}
+
+func (c *commandIndex) html() ([]byte, error) {
+	rendered, err := commandsTmpl.render(c)
+	if err != nil {
+		e := fmt.Errorf("failed to render commands html: %w", err)
+		return nil, e
+	}
+	return rendered, nil
+}
+
+// commandAvailability computes, for every command, the ranges of versions it appears in and its sections
+func commandAvailability(db bitcoind.RpcDb) map[string]*availability {
+	versions := versionsAscending(db)
+	avail := make(map[string]*availability)
+	for i, v := range versions {
+		for sec, cmds := range db[v] {
+			for _, cmd := range cmds {
+				a, ok := avail[cmd.Name]
+				if !ok {
+					a = &availability{Name: cmd.Name}
+					avail[cmd.Name] = a
+				}
+				a.add(versions, i)
+				a.LastSection = sec
+				if !slices.Contains(a.Sections, sec) {
+					a.Sections = append(a.Sections, sec)
+				}
+			}
+		}
+	}
+	return avail
+}
+
+func newCommandIndex(latest string, avail map[string]*availability) *commandIndex {
+	idx := &commandIndex{Latest: latest, Commands: make([]*availability, 0, len(avail))}
+	for _, a := range avail {
+		idx.Commands = append(idx.Commands, a)
+	}
+	slices.SortFunc(idx.Commands, func(a, b *availability) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return idx
+}
@@ -0,0 +1,68 @@
+package gensite
+
+import (
+	"bitcoinrpcschema/internal/bitcoind"
+	coreversion "bitcoinrpcschema/internal/version"
+	_ "embed"
+	"fmt"
+)
+
+//go:embed changelog.html
+var changelogHtml string
+
+var changelogTmpl = mustBtcTemplate("changelog", changelogHtml)
+
+const hiddenSection = "hidden"
+
+// changelog is the page summarizing the RPC changes of a release against the previous one
+type changelog struct {
+	Version  string
+	Previous string
+	Added    []*commandDiff
+	Removed  []*commandDiff
+	Moved    []*commandDiff
+	Hidden   []*commandDiff
+	Unhidden []*commandDiff
+	Changed  []*commandDiff
+}
+
+func (c *changelog) html() ([]byte, error) {
+	rendered, err := changelogTmpl.render(c)
+	if err != nil {
+		e := fmt.Errorf("failed to render changelog html for %s: %w", c.Version, err)
+		return nil, e
+	}
+	return rendered, nil
+}
+
+// addChangelogs adds a changelog page for every version, built from the command diffs against its predecessor
+func addChangelogs(site site, db bitcoind.RpcDb, previous map[coreversion.Version]coreversion.Version, diffs map[coreversion.Version][]*commandDiff) error {
+	for rv := range db {
+		c := &changelog{Version: rv.String()}
+		if prev, ok := previous[rv]; ok {
+			c.Previous = prev.String()
+		}
+		for _, d := range diffs[rv] {
+			switch {
+			case d.FromSection == "":
+				c.Added = append(c.Added, d)
+			case d.ToSection == "":
+				c.Removed = append(c.Removed, d)
+			case d.FromSection != d.ToSection && d.ToSection == hiddenSection:
+				c.Hidden = append(c.Hidden, d)
+			case d.FromSection != d.ToSection && d.FromSection == hiddenSection:
+				c.Unhidden = append(c.Unhidden, d)
+			case d.FromSection != d.ToSection:
+				c.Moved = append(c.Moved, d)
+			}
+			if d.FromSection != "" && d.ToSection != "" && d.HelpChanged {
+				c.Changed = append(c.Changed, d)
+			}
+		}
+		err := site.add(rv.String()+"/changes/index.html", c)
+		if err != nil {
+			return fmt.Errorf("failed to add changelog for %s to site: %w", rv, err)
+		}
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package gensite
 
 import (
+	"bitcoinrpcschema/internal/bitcoind"
 	"bufio"
 	_ "embed"
 	"fmt"
@@ -11,11 +12,16 @@ import (
 var commandHtml string
 
 type command struct {
-	Version     string
-	Section     string
-	Name        string
-	DateTime    string
-	Description string
+	Version        string
+	Section        string
+	Name           string
+	DateTime       string
+	Description    string
+	Arguments      []bitcoind.Argument
+	Results        []bitcoind.Result
+	RequiresWallet bool
+	Previous       string
+	Available      *availability
 }
 
 type parsedDescription struct {
@@ -0,0 +1,93 @@
+package gensite
+
+import (
+	"bitcoinrpcschema/internal/bitcoind"
+	coreversion "bitcoinrpcschema/internal/version"
+	_ "embed"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+//go:embed config.html
+var configHtml string
+
+var configTmpl = mustBtcTemplate("config", configHtml)
+
+// configPage lists the startup options of a release, and how they changed since the previous one
+type configPage struct {
+	Version  string
+	Previous string
+	Groups   []bitcoind.OptionGroup
+	Added    []bitcoind.ConfigOption
+	Removed  []bitcoind.ConfigOption
+	Changed  []optionChange
+}
+
+type optionChange struct {
+	From bitcoind.ConfigOption
+	To   bitcoind.ConfigOption
+}
+
+func (c *configPage) html() ([]byte, error) {
+	rendered, err := configTmpl.render(c)
+	if err != nil {
+		e := fmt.Errorf("failed to render config html for %s: %w", c.Version, err)
+		return nil, e
+	}
+	return rendered, nil
+}
+
+// addConfigPages adds a configuration options page for every release whose options were captured
+func addConfigPages(site site, db *bitcoind.Db, previous map[coreversion.Version]coreversion.Version) error {
+	for rv, release := range db.Releases {
+		if len(release.Options) == 0 {
+			continue
+		}
+		c := &configPage{Version: rv.String(), Groups: release.Options}
+		if prev, ok := previous[rv]; ok && len(db.Releases[prev].Options) > 0 {
+			c.Previous = prev.String()
+			c.Added, c.Removed, c.Changed = diffOptions(db.Releases[prev].Options, release.Options)
+		}
+		err := site.add(configPath(rv)+"/index.html", c)
+		if err != nil {
+			return fmt.Errorf("failed to add config options for %s to site: %w", rv, err)
+		}
+	}
+	return nil
+}
+
+func configPath(rv coreversion.Version) string {
+	return rv.String() + "/config"
+}
+
+// diffOptions compares options by name, regardless of their group
+func diffOptions(from, to []bitcoind.OptionGroup) (added, removed []bitcoind.ConfigOption, changed []optionChange) {
+	before := optionsByName(from)
+	after := optionsByName(to)
+	for _, name := range slices.Sorted(maps.Keys(after)) {
+		o, ok := before[name]
+		switch {
+		case !ok:
+			added = append(added, after[name])
+		case o != after[name]:
+			changed = append(changed, optionChange{From: o, To: after[name]})
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(before)) {
+		if _, ok := after[name]; !ok {
+			removed = append(removed, before[name])
+		}
+	}
+	return added, removed, changed
+}
+
+func optionsByName(groups []bitcoind.OptionGroup) map[string]bitcoind.ConfigOption {
+	m := make(map[string]bitcoind.ConfigOption)
+	for _, g := range groups {
+		for _, o := range g.Options {
+			m[o.Name] = o
+		}
+	}
+	return m
+}
@@ -0,0 +1,334 @@
+package gensite
+
+import (
+	"bitcoinrpcschema/internal/bitcoind"
+	coreversion "bitcoinrpcschema/internal/version"
+	_ "embed"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+//go:embed diff.html
+var diffHtml string
+
+//go:embed diffs.html
+var diffsHtml string
+
+var diffTmpl = mustBtcTemplate("diff", diffHtml)
+var diffsTmpl = mustBtcTemplate("diffs", diffsHtml)
+
+// commandDiff is the page comparing one command between two adjacent versions
+type commandDiff struct {
+	From        string
+	To          string
+	Name        string
+	FromSection string
+	ToSection   string
+	HelpChanged bool
+	Usage       []lineChange
+	Explanation []lineChange
+	Arguments   []fieldChange
+	Results     []fieldChange
+}
+
+// versionDiff is the page listing the command diffs between two adjacent versions
+type versionDiff struct {
+	From      string
+	To        string
+	Added     []string
+	Removed   []string
+	Changed   []string
+	Unchanged []string
+}
+
+type lineChange struct {
+	Op   string
+	Text string
+}
+
+type fieldChange struct {
+	Path   string
+	Change string
+	From   string
+	To     string
+}
+
+func (d *commandDiff) html() ([]byte, error) {
+	rendered, err := diffTmpl.render(d)
+	if err != nil {
+		e := fmt.Errorf("failed to render diff html for %s: %w", d.Name, err)
+		return nil, e
+	}
+	return rendered, nil
+}
+
+func (d *commandDiff) changed() bool {
+	return d.FromSection != d.ToSection || d.HelpChanged
+}
+
+func (d *versionDiff) html() ([]byte, error) {
+	rendered, err := diffsTmpl.render(d)
+	if err != nil {
+		e := fmt.Errorf("failed to render diffs html for %s..%s: %w", d.From, d.To, err)
+		return nil, e
+	}
+	return rendered, nil
+}
+
+// diffPath is the directory holding the diffs between two versions
+func diffPath(from, to coreversion.Version) string {
+	return fmt.Sprintf("diff/%s..%s", from, to)
+}
+
+// addDiffs adds a diff page for every command in either of each pair of adjacent versions.
+// It returns the diffs keyed by the later version of each pair.
+func addDiffs(site site, db bitcoind.RpcDb, previous map[coreversion.Version]coreversion.Version) (map[coreversion.Version][]*commandDiff, error) {
+	diffs := make(map[coreversion.Version][]*commandDiff, len(previous))
+	for to, from := range previous {
+		fromCmds := commandsByName(db[from])
+		toCmds := commandsByName(db[to])
+		vd := &versionDiff{From: from.String(), To: to.String()}
+
+		for _, name := range commandNamesUnion(fromCmds, toCmds) {
+			d, err := diffCommand(from, to, name, fromCmds[name], toCmds[name])
+			if err != nil {
+				return nil, fmt.Errorf("failed to diff command %s: %w", name, err)
+			}
+			diffs[to] = append(diffs[to], d)
+			switch {
+			case d.FromSection == "":
+				vd.Added = append(vd.Added, name)
+			case d.ToSection == "":
+				vd.Removed = append(vd.Removed, name)
+			case d.changed():
+				vd.Changed = append(vd.Changed, name)
+			default:
+				vd.Unchanged = append(vd.Unchanged, name)
+			}
+			err = site.add(fmt.Sprintf("%s/%s/index.html", diffPath(from, to), name), d)
+			if err != nil {
+				return nil, fmt.Errorf("failed to add diff of %s to site: %w", name, err)
+			}
+		}
+
+		err := site.add(diffPath(from, to)+"/index.html", vd)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add diffs %s..%s to site: %w", from, to, err)
+		}
+	}
+	return diffs, nil
+}
+
+// sectionCommand is a command together with the section it belongs to
+type sectionCommand struct {
+	Section string
+	bitcoind.Command
+}
+
+func commandsByName(sections map[string][]bitcoind.Command) map[string]sectionCommand {
+	m := make(map[string]sectionCommand)
+	for sec, cmds := range sections {
+		for _, cmd := range cmds {
+			m[cmd.Name] = sectionCommand{sec, cmd}
+		}
+	}
+	return m
+}
+
+func commandNamesUnion(a, b map[string]sectionCommand) []string {
+	names := make([]string, 0, len(a))
+	for name := range a {
+		names = append(names, name)
+	}
+	for name := range b {
+		if _, ok := a[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+func diffCommand(from, to coreversion.Version, name string, fromCmd, toCmd sectionCommand) (*commandDiff, error) {
+	fromDesc, err := parseDescription(fromCmd.Help)
+	if err != nil {
+		return nil, err
+	}
+	toDesc, err := parseDescription(toCmd.Help)
+	if err != nil {
+		return nil, err
+	}
+	return &commandDiff{
+		From:        from.String(),
+		To:          to.String(),
+		Name:        name,
+		FromSection: fromCmd.Section,
+		ToSection:   toCmd.Section,
+		HelpChanged: fromCmd.Help != toCmd.Help,
+		Usage:       diffLines(nonEmpty(fromDesc.Usage), nonEmpty(toDesc.Usage)),
+		Explanation: diffLines(fromDesc.Explanation, toDesc.Explanation),
+		Arguments:   diffFields(flattenArguments(fromCmd.Arguments), flattenArguments(toCmd.Arguments)),
+		Results:     diffFields(flattenResults(fromCmd.Results), flattenResults(toCmd.Results)),
+	}, nil
+}
+
+func nonEmpty(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return []string{s}
+}
+
+func hasLineChanges(lines []lineChange) bool {
+	return slices.ContainsFunc(lines, func(l lineChange) bool { return l.Op != " " })
+}
+
+// diffLines returns a line diff of a and b, based on their longest common subsequence
+func diffLines(a, b []string) []lineChange {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var changes []lineChange
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			changes = append(changes, lineChange{" ", a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			changes = append(changes, lineChange{"-", a[i]})
+			i++
+		default:
+			changes = append(changes, lineChange{"+", b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		changes = append(changes, lineChange{"-", a[i]})
+	}
+	for ; j < len(b); j++ {
+		changes = append(changes, lineChange{"+", b[j]})
+	}
+	return changes
+}
+
+// flatField is a field of a nested argument or result, identified by its path, e.g. "inputs[].txid"
+type flatField struct {
+	path    string
+	summary string
+}
+
+func flattenArguments(args []bitcoind.Argument) []flatField {
+	var fields []flatField
+	var flatten func(prefix string, inArray bool, args []bitcoind.Argument)
+	flatten = func(prefix string, inArray bool, args []bitcoind.Argument) {
+		for i, a := range args {
+			path := argumentName(a)
+			if inArray {
+				path = prefix + elementPath(i, len(args))
+			} else if prefix != "" {
+				path = prefix + "." + path
+			}
+			fields = append(fields, flatField{path, argumentSummary(a)})
+			flatten(path, a.Type == "json array", a.Fields)
+		}
+	}
+	flatten("", false, args)
+	return fields
+}
+
+// elementPath is "[]" for arrays with a single kind of element, and "[i]" for the alternatives of arrays with several
+func elementPath(i, n int) string {
+	if n == 1 {
+		return "[]"
+	}
+	return fmt.Sprintf("[%d]", i)
+}
+
+func argumentSummary(a bitcoind.Argument) string {
+	var b strings.Builder
+	b.WriteString("(" + a.Type)
+	if a.Required {
+		b.WriteString(", required")
+	}
+	if a.Optional {
+		b.WriteString(", optional")
+	}
+	if a.HasDefault {
+		b.WriteString(", default=" + a.Default)
+	}
+	b.WriteString(") " + a.Description)
+	return strings.TrimSpace(b.String())
+}
+
+func flattenResults(results []bitcoind.Result) []flatField {
+	var fields []flatField
+	var flatten func(path string, f bitcoind.ResultField)
+	flatten = func(path string, f bitcoind.ResultField) {
+		fields = append(fields, flatField{path, resultSummary(f)})
+		for i, inner := range f.Fields {
+			if f.Type == "json array" {
+				flatten(path+elementPath(i, len(f.Fields)), inner)
+			} else {
+				flatten(path+"."+inner.Key, inner)
+			}
+		}
+	}
+	for _, r := range results {
+		root := "result"
+		if r.Condition != "" {
+			root = fmt.Sprintf("result (%s)", r.Condition)
+		}
+		flatten(root, r.Value)
+	}
+	return fields
+}
+
+func resultSummary(f bitcoind.ResultField) string {
+	optional := ""
+	if f.Optional {
+		optional = ", optional"
+	}
+	return strings.TrimSpace(fmt.Sprintf("(%s%s) %s", f.Type, optional, f.Description))
+}
+
+// diffFields lists the fields added, removed or changed between a and b, in the order they appear
+func diffFields(a, b []flatField) []fieldChange {
+	before := make(map[string]string, len(a))
+	for _, f := range a {
+		before[f.path] = f.summary
+	}
+	after := make(map[string]string, len(b))
+	for _, f := range b {
+		after[f.path] = f.summary
+	}
+
+	var changes []fieldChange
+	for _, f := range b {
+		old, ok := before[f.path]
+		if !ok {
+			changes = append(changes, fieldChange{Path: f.path, Change: "added", To: f.summary})
+		} else if old != f.summary {
+			changes = append(changes, fieldChange{Path: f.path, Change: "changed", From: old, To: f.summary})
+		}
+	}
+	for _, f := range a {
+		if _, ok := after[f.path]; !ok {
+			changes = append(changes, fieldChange{Path: f.path, Change: "removed", From: f.summary})
+		}
+	}
+	return changes
+}
@@ -2,6 +2,7 @@ package gensite
 
 import (
 	"bitcoinrpcschema/internal/bitcoind"
+	coreversion "bitcoinrpcschema/internal/version"
 	_ "embed"
 	"fmt"
 	"slices"
@@ -11,26 +12,42 @@ import (
 var picoCss []byte
 
 func Gen(db []byte, webPath string) error {
-	rpcDb, err := bitcoind.ReadDb(db)
+	fullDb, err := bitcoind.ReadDb(db)
 	if err != nil {
 		return err
 	}
+	rpcDb := fullDb.Rpcs()
 
+	previous := previousVersions(rpcDb)
+	avail := commandAvailability(rpcDb)
 	site := newSite()
 	for rv, sections := range rpcDb {
+		var prev string
+		if p, ok := previous[rv]; ok {
+			prev = p.String()
+		}
 		for sec, cmds := range sections {
 			for _, cmd := range cmds {
 				p := fmt.Sprintf("%s/%s/%s/index.html", rv, sec, cmd.Name)
 				c := &command{
-					Version:     rv.String(),
-					Section:     sec,
-					Name:        cmd.Name,
-					Description: cmd.Help,
+					Version:        rv.String(),
+					Section:        sec,
+					Name:           cmd.Name,
+					Description:    cmd.Help,
+					Arguments:      cmd.Arguments,
+					Results:        cmd.Results,
+					RequiresWallet: cmd.RequiresWallet,
+					Previous:       prev,
+					Available:      avail[cmd.Name],
 				}
 				err := site.add(p, c)
 				if err != nil {
 					return fmt.Errorf("failed to add command %s to site: %w", cmd.Name, err)
 				}
+				err = addCommandSchemas(site, rv, cmd)
+				if err != nil {
+					return fmt.Errorf("failed to add command %s schemas to site: %w", cmd.Name, err)
+				}
 			}
 			p := fmt.Sprintf("%s/%s/index.html", rv, sec)
 			s := &section{
@@ -43,16 +60,59 @@ func Gen(db []byte, webPath string) error {
 				return fmt.Errorf("failed to add section %s to site: %w", sec, err)
 			}
 		}
+		doc, err := openRpc(rv, sections)
+		if err != nil {
+			return fmt.Errorf("failed to generate OpenRPC document for version %s: %w", rv.String(), err)
+		}
+		site.addRaw(rv.String()+"/openrpc.json", doc)
+
+		shard, err := searchIndex(sections)
+		if err != nil {
+			return fmt.Errorf("failed to generate search index for version %s: %w", rv.String(), err)
+		}
+		site.addRaw("search/"+rv.String()+".json", shard)
+
+		err = addApi(site, rv, sections, avail)
+		if err != nil {
+			return fmt.Errorf("failed to add API for version %s: %w", rv.String(), err)
+		}
+
 		name := rv.String()
 		p := name + "/index.html"
 		sections := cmdNamesBySection(sections)
-		v := version{name, sections}
-		err := site.add(p, &v)
+		release := fullDb.Releases[rv]
+		v := version{Name: name, Sections: sections, Previous: prev, PreRelease: !rv.IsRelease(), HasConfig: len(release.Options) > 0, HasRest: len(release.Rest) > 0, HasZmq: release.Zmq != nil, Tools: toolNames(release)}
+		err = site.add(p, &v)
 		if err != nil {
 			return fmt.Errorf("failed to add version %s to site: %w", rv.String(), err)
 		}
 	}
 
+	diffs, err := addDiffs(site, rpcDb, previous)
+	if err != nil {
+		return fmt.Errorf("failed to add diffs: %w", err)
+	}
+	err = addChangelogs(site, rpcDb, previous, diffs)
+	if err != nil {
+		return fmt.Errorf("failed to add changelogs: %w", err)
+	}
+	err = addConfigPages(site, fullDb, previous)
+	if err != nil {
+		return fmt.Errorf("failed to add config options: %w", err)
+	}
+	err = addToolPages(site, fullDb)
+	if err != nil {
+		return fmt.Errorf("failed to add tools: %w", err)
+	}
+	err = addRestPages(site, fullDb, previous)
+	if err != nil {
+		return fmt.Errorf("failed to add REST endpoints: %w", err)
+	}
+	err = addZmqPages(site, fullDb)
+	if err != nil {
+		return fmt.Errorf("failed to add ZMQ topics: %w", err)
+	}
+
 	idx := &index{}
 	idx.Latest, idx.Versions, err = versionsDescending(rpcDb)
 	if err != nil {
@@ -62,6 +122,19 @@ func Gen(db []byte, webPath string) error {
 	if err != nil {
 		return fmt.Errorf("failed to add index to site: %w", err)
 	}
+	err = site.addJson("api/versions.json", apiVersions{Latest: idx.Latest, Versions: append([]string{idx.Latest}, idx.Versions...)})
+	if err != nil {
+		return fmt.Errorf("failed to add API versions to site: %w", err)
+	}
+	err = site.add("commands/index.html", newCommandIndex(idx.Latest, avail))
+	if err != nil {
+		return fmt.Errorf("failed to add command index to site: %w", err)
+	}
+	err = site.add("search/index.html", &searchPage{Versions: append([]string{idx.Latest}, idx.Versions...)})
+	if err != nil {
+		return fmt.Errorf("failed to add search to site: %w", err)
+	}
+	site.addRaw("search/search.js", searchJs)
 
 	site.addRaw("pico.min.css", picoCss)
 
@@ -72,6 +145,23 @@ func Gen(db []byte, webPath string) error {
 	return nil
 }
 
+func addCommandSchemas(site site, rv coreversion.Version, cmd bitcoind.Command) error {
+	paramsPath := fmt.Sprintf("%s/schema/%s.params.json", rv, cmd.Name)
+	params, err := standaloneSchema(paramsSchema(cmd.Arguments), cmd.Name+" params", paramsPath)
+	if err != nil {
+		return err
+	}
+	site.addRaw(paramsPath, params)
+
+	resultPath := fmt.Sprintf("%s/schema/%s.result.json", rv, cmd.Name)
+	result, err := standaloneSchema(resultsSchema(cmd.Results), cmd.Name+" result", resultPath)
+	if err != nil {
+		return err
+	}
+	site.addRaw(resultPath, result)
+	return nil
+}
+
 func cmdNames(cmds []bitcoind.Command) []string {
 	cmdNames := make([]string, len(cmds))
 	for i, cmd := range cmds {
@@ -90,21 +180,56 @@ func cmdNamesBySection(commands map[string][]bitcoind.Command) map[string][]stri
 	return sections
 }
 
+// previousVersions maps each version to the version it is compared with, if any. A final release is compared
+// with the preceding final release, so that release candidates and builds in between don't hide its changes.
+// Other versions are compared with the version preceding them.
+func previousVersions(db bitcoind.RpcDb) map[coreversion.Version]coreversion.Version {
+	versions := versionsAscending(db)
+	previous := make(map[coreversion.Version]coreversion.Version, len(versions))
+	var lastRelease *coreversion.Version
+	for i, v := range versions {
+		switch {
+		case v.IsRelease():
+			if lastRelease != nil {
+				previous[v] = *lastRelease
+			}
+			lastRelease = &versions[i]
+		case i > 0:
+			previous[v] = versions[i-1]
+		}
+	}
+	return previous
+}
+
+func versionsAscending(db bitcoind.RpcDb) []coreversion.Version {
+	versions := make([]coreversion.Version, 0, len(db))
+	for v := range db {
+		versions = append(versions, v)
+	}
+	slices.SortFunc(versions, func(a, b coreversion.Version) int {
+		return a.Cmp(b)
+	})
+	return versions
+}
+
 func versionsDescending(db bitcoind.RpcDb) (string, []string, error) {
 	if len(db) < 1 {
 		return "", nil, fmt.Errorf("empty database")
 	}
-	versions := make([]bitcoind.ReleaseVersion, 0, len(db))
+	versions := make([]coreversion.Version, 0, len(db))
 	for v := range db {
 		versions = append(versions, v)
 	}
-	slices.SortFunc(versions, func(a, b bitcoind.ReleaseVersion) int {
+	slices.SortFunc(versions, func(a, b coreversion.Version) int {
 		return -a.Cmp(b)
 	})
-	latest := versions[0].String()
-	other := make([]string, len(versions)-1)
-	for i, v := range versions[1:] {
-		other[i] = v.String()
+	// the latest release, unless there are only pre-releases and other builds
+	latest := max(slices.IndexFunc(versions, coreversion.Version.IsRelease), 0)
+	other := make([]string, 0, len(versions)-1)
+	for i, v := range versions {
+		if i != latest {
+			other = append(other, v.String())
+		}
 	}
-	return latest, other, nil
+	return versions[latest].String(), other, nil
 }
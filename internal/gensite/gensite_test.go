@@ -3,7 +3,9 @@ package gensite_test
 import (
 	"bitcoinrpcschema/internal/bitcoind"
 	"bitcoinrpcschema/internal/gensite"
+	"bitcoinrpcschema/internal/version"
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -41,8 +43,8 @@ func init() {
 			_, _ = fmt.Fprintf(os.Stderr, "error removing temp dir %s: %v\n", webDir, err)
 		}
 	}
-	db := bitcoind.RpcDb{
-		bitcoind.ReleaseVersion{Major: 1, Minor: 2, Patch: 3}: {
+	rpcs := bitcoind.RpcDb{
+		version.Version{Major: 1, Minor: 2, Patch: 3}: {
 			"section1": {
 				{Name: "cmd1", Help: "help1"},
 				{Name: "cmd2", Help: "help2"},
@@ -52,18 +54,71 @@ func init() {
 				{Name: "cmd4", Help: "help4"},
 			},
 		},
-		bitcoind.ReleaseVersion{Major: 2, Minor: 3, Patch: 4}: {
+		version.Version{Major: 2, Minor: 3, Patch: 4}: {
 			"section1": {
 				{Name: "cmd1", Help: "help1"},
 				{Name: "cmd2", Help: "help2"},
 			},
 			"section2": {
-				{Name: "cmd3", Help: "help3"},
+				{Name: "cmd3", Help: "help3", RequiresWallet: true},
 				{Name: "cmd4", Help: "help4-old"},
 			},
 		},
 	}
 
+	db := bitcoind.NewDb(rpcs)
+	db.Releases[version.Version{Major: 1, Minor: 2, Patch: 3}].Options = []bitcoind.OptionGroup{
+		{Name: "Options", Options: []bitcoind.ConfigOption{
+			{Name: "opt1", Value: "<n>", Default: "1", Description: "option 1 (default: 1)"},
+			{Name: "opt2", Description: "option 2"},
+		}},
+	}
+	db.Releases[version.Version{Major: 2, Minor: 3, Patch: 4}].Options = []bitcoind.OptionGroup{
+		{Name: "Options", Options: []bitcoind.ConfigOption{
+			{Name: "opt1", Value: "<n>", Default: "2", Description: "option 1 (default: 2)"},
+			{Name: "opt3", Description: "option 3", Debug: true},
+		}},
+	}
+
+	db.Releases[version.Version{Major: 1, Minor: 2, Patch: 3}].Rest = []bitcoind.RestEndpoint{
+		{Path: "/rest/tx/", Formats: []string{"bin", "hex", "json"}},
+		{Path: "/rest/old/", Formats: []string{"json"}},
+	}
+	db.Releases[version.Version{Major: 2, Minor: 3, Patch: 4}].Rest = []bitcoind.RestEndpoint{
+		{Path: "/rest/tx/", Formats: []string{"bin", "hex", "json"}},
+		{Path: "/rest/new/", Formats: []string{"json"}},
+	}
+	db.Releases[version.Version{Major: 1, Minor: 2, Patch: 3}].Zmq = []bitcoind.ZmqTopic{
+		{Topic: "hashblock", Option: "zmqpubhashblock", Description: "Enable publish hash block in <address>"},
+	}
+	db.Releases[version.Version{Major: 2, Minor: 3, Patch: 4}].Zmq = []bitcoind.ZmqTopic{
+		{Topic: "hashblock", Option: "zmqpubhashblock", Description: "Enable publish hash block in <address>"},
+		{Topic: "sequence", Option: "zmqpubsequence", Description: "Enable publish hash block and tx sequence in <address>",
+			Labels: []bitcoind.ZmqLabel{{Label: "C", Description: "Block (C)onnect"}}, Reported: true, Hwm: 1000},
+	}
+	db.Releases[version.Version{Major: 2, Minor: 3, Patch: 4}].Tools = map[string]bitcoind.ToolHelp{
+		"bitcoin-cli": {
+			Usage: []string{"bitcoin-cli [options] <command> [params]  Send command to Bitcoin Core"},
+			Groups: []bitcoind.OptionGroup{
+				{Name: "Options", Options: []bitcoind.ConfigOption{{Name: "named", Description: "Pass named instead of positional arguments"}}},
+			},
+		},
+		"bitcoin-wallet": {
+			Usage: []string{"bitcoin-wallet [options] <command>"},
+			Groups: []bitcoind.OptionGroup{
+				{Name: "Options", Options: []bitcoind.ConfigOption{{Name: "wallet", Value: "<wallet-name>", Description: "Specify wallet name"}}},
+			},
+			Commands: []bitcoind.ToolCommand{{Group: "Commands", Name: "create", Description: "Create new wallet file"}},
+		},
+		"bitcoin-tx": {
+			Usage: []string{"bitcoin-tx [options] <hex-tx> [commands]  Update hex-encoded bitcoin transaction"},
+			Commands: []bitcoind.ToolCommand{
+				{Group: "Commands", Name: "delin", Value: "N", Description: "Delete input N from TX"},
+				{Group: "Register Commands", Name: "load", Value: "NAME:FILENAME", Description: "Load JSON file FILENAME into register NAME"},
+			},
+		},
+	}
+
 	func() {
 		defer cleanup()
 		dbBytes, err := db.Marshal()
@@ -78,22 +133,72 @@ func init() {
 // test the pages we expect are generated
 func TestGeneratedPages(t *testing.T) {
 	expected := []string{
+		"1.2.3/changes/index.html",
+		"1.2.3/config/index.html",
 		"1.2.3/index.html",
+		"1.2.3/openrpc.json",
+		"1.2.3/rest/index.html",
+		"1.2.3/schema/cmd1.params.json",
+		"1.2.3/schema/cmd1.result.json",
+		"1.2.3/schema/cmd2.params.json",
+		"1.2.3/schema/cmd2.result.json",
+		"1.2.3/schema/cmd3.params.json",
+		"1.2.3/schema/cmd3.result.json",
+		"1.2.3/schema/cmd4.params.json",
+		"1.2.3/schema/cmd4.result.json",
 		"1.2.3/section1/cmd1/index.html",
 		"1.2.3/section1/cmd2/index.html",
 		"1.2.3/section1/index.html",
 		"1.2.3/section2/cmd3/index.html",
 		"1.2.3/section2/cmd4/index.html",
 		"1.2.3/section2/index.html",
+		"1.2.3/zmq-notifications/index.html",
+		"2.3.4/changes/index.html",
+		"2.3.4/config/index.html",
 		"2.3.4/index.html",
+		"2.3.4/openrpc.json",
+		"2.3.4/rest/index.html",
+		"2.3.4/schema/cmd1.params.json",
+		"2.3.4/schema/cmd1.result.json",
+		"2.3.4/schema/cmd2.params.json",
+		"2.3.4/schema/cmd2.result.json",
+		"2.3.4/schema/cmd3.params.json",
+		"2.3.4/schema/cmd3.result.json",
+		"2.3.4/schema/cmd4.params.json",
+		"2.3.4/schema/cmd4.result.json",
 		"2.3.4/section1/cmd1/index.html",
 		"2.3.4/section1/cmd2/index.html",
 		"2.3.4/section1/index.html",
 		"2.3.4/section2/cmd3/index.html",
 		"2.3.4/section2/cmd4/index.html",
 		"2.3.4/section2/index.html",
+		"2.3.4/tools/bitcoin-cli/index.html",
+		"2.3.4/tools/bitcoin-tx/index.html",
+		"2.3.4/tools/bitcoin-wallet/index.html",
+		"2.3.4/zmq-notifications/index.html",
+		"api/1.2.3/cmd1.json",
+		"api/1.2.3/cmd2.json",
+		"api/1.2.3/cmd3.json",
+		"api/1.2.3/cmd4.json",
+		"api/1.2.3/commands.json",
+		"api/2.3.4/cmd1.json",
+		"api/2.3.4/cmd2.json",
+		"api/2.3.4/cmd3.json",
+		"api/2.3.4/cmd4.json",
+		"api/2.3.4/commands.json",
+		"api/versions.json",
+		"commands/index.html",
+		"diff/1.2.3..2.3.4/cmd1/index.html",
+		"diff/1.2.3..2.3.4/cmd2/index.html",
+		"diff/1.2.3..2.3.4/cmd3/index.html",
+		"diff/1.2.3..2.3.4/cmd4/index.html",
+		"diff/1.2.3..2.3.4/index.html",
 		"index.html",
 		"pico.min.css",
+		"search/1.2.3.json",
+		"search/2.3.4.json",
+		"search/index.html",
+		"search/search.js",
 	}
 	generated := make([]string, 0, len(generatedSite))
 	for path := range generatedSite {
@@ -103,6 +208,230 @@ func TestGeneratedPages(t *testing.T) {
 	assert.Equal(t, expected, generated)
 }
 
+func TestOpenRpc(t *testing.T) {
+	var doc struct {
+		OpenRpc string `json:"openrpc"`
+		Info    struct {
+			Version string `json:"version"`
+		} `json:"info"`
+		Methods []struct {
+			Name string `json:"name"`
+			Tags []struct {
+				Name string `json:"name"`
+			} `json:"tags"`
+		} `json:"methods"`
+	}
+	err := json.Unmarshal(generatedSite["2.3.4/openrpc.json"], &doc)
+	require.NoError(t, err)
+	assert.Equal(t, "1.3.2", doc.OpenRpc)
+	assert.Equal(t, "2.3.4", doc.Info.Version)
+	methods := make([]string, len(doc.Methods))
+	for i, m := range doc.Methods {
+		require.Len(t, m.Tags, 1)
+		methods[i] = m.Tags[0].Name + "/" + m.Name
+	}
+	assert.Equal(t, []string{"section1/cmd1", "section1/cmd2", "section2/cmd3", "section2/cmd4"}, methods)
+}
+
+func TestJsonSchema(t *testing.T) {
+	var schema map[string]any
+	err := json.Unmarshal(generatedSite["1.2.3/schema/cmd1.params.json"], &schema)
+	require.NoError(t, err)
+	assert.Equal(t, "https://json-schema.org/draft/2020-12/schema", schema["$schema"])
+	assert.Equal(t, "https://bitcoinrpc.dev/1.2.3/schema/cmd1.params.json", schema["$id"])
+	assert.Equal(t, "array", schema["type"])
+	assert.Equal(t, 0.0, schema["maxItems"])
+}
+
+func TestDiff(t *testing.T) {
+	diffs := string(generatedSite["diff/1.2.3..2.3.4/index.html"])
+	changed, unchanged, found := strings.Cut(diffs, "Unchanged")
+	require.True(t, found)
+	assert.Contains(t, changed, `href=cmd4/`)
+	assert.NotContains(t, changed, `href=cmd1/`)
+	assert.Contains(t, unchanged, `href=cmd1/`)
+
+	cmd4 := string(generatedSite["diff/1.2.3..2.3.4/cmd4/index.html"])
+	assert.Contains(t, cmd4, "<del>- help4</del>")
+	assert.Contains(t, cmd4, "<ins>+ help4-old</ins>")
+}
+
+func TestChangelog(t *testing.T) {
+	changes := string(generatedSite["2.3.4/changes/index.html"])
+	assert.Contains(t, changes, "Help changed")
+	assert.Contains(t, changes, `href=/diff/1.2.3..2.3.4/cmd4/`)
+	assert.NotContains(t, changes, "cmd1")
+}
+
+func TestCommandIndex(t *testing.T) {
+	commands := string(generatedSite["commands/index.html"])
+	assert.Contains(t, commands, `<a href=/2.3.4/section1/cmd1/>cmd1</a>`)
+	assert.Contains(t, string(generatedSite["2.3.4/section1/cmd1/index.html"]), `Available since <a href=/1.2.3/>1.2.3</a>`)
+}
+
+func TestSearchIndex(t *testing.T) {
+	var entries []struct {
+		Name    string `json:"name"`
+		Section string `json:"section"`
+	}
+	err := json.Unmarshal(generatedSite["search/2.3.4.json"], &entries)
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	assert.Equal(t, "cmd1", entries[0].Name)
+	assert.Equal(t, "section1", entries[0].Section)
+	assert.Contains(t, string(generatedSite["search/index.html"]), `<option value=2.3.4>2.3.4`)
+}
+
+func TestApi(t *testing.T) {
+	var versions struct {
+		Latest   string   `json:"latest"`
+		Versions []string `json:"versions"`
+	}
+	err := json.Unmarshal(generatedSite["api/versions.json"], &versions)
+	require.NoError(t, err)
+	assert.Equal(t, "2.3.4", versions.Latest)
+	assert.Equal(t, []string{"2.3.4", "1.2.3"}, versions.Versions)
+
+	var cmd struct {
+		Name         string `json:"name"`
+		Section      string `json:"section"`
+		Help         string `json:"help"`
+		Availability struct {
+			First string `json:"first"`
+		} `json:"availability"`
+	}
+	err = json.Unmarshal(generatedSite["api/2.3.4/cmd4.json"], &cmd)
+	require.NoError(t, err)
+	assert.Equal(t, "cmd4", cmd.Name)
+	assert.Equal(t, "section2", cmd.Section)
+	assert.Equal(t, "help4-old", cmd.Help)
+	assert.Equal(t, "1.2.3", cmd.Availability.First)
+}
+
+func TestConfigOptions(t *testing.T) {
+	page := string(generatedSite["2.3.4/config/index.html"])
+	assert.Contains(t, page, "<h2>Changes since <a href=/1.2.3/config/>1.2.3</a></h2>")
+	assert.Contains(t, page, "<li><a href=#opt3>-opt3</a>")
+	assert.Contains(t, page, "<li><a href=/1.2.3/config/#opt2>-opt2</a>")
+	assert.Contains(t, page, "default <code>1</code> became <code>2</code>")
+	assert.Contains(t, page, "<code>-opt3</code> <small>(debug)</small>")
+	assert.NotContains(t, string(generatedSite["1.2.3/config/index.html"]), "Changes since")
+}
+
+func TestTools(t *testing.T) {
+	assert.Contains(t, string(generatedSite["2.3.4/index.html"]), "<a href=tools/bitcoin-cli/>bitcoin-cli</a>")
+	assert.Contains(t, string(generatedSite["2.3.4/tools/bitcoin-cli/index.html"]), "<code>-named</code>")
+	wallet := string(generatedSite["2.3.4/tools/bitcoin-wallet/index.html"])
+	assert.Contains(t, wallet, "<code>-wallet=&lt;wallet-name></code>")
+	assert.Contains(t, wallet, "<h2>Commands</h2>")
+	assert.Contains(t, wallet, "<code>create</code>")
+	tx := string(generatedSite["2.3.4/tools/bitcoin-tx/index.html"])
+	assert.Contains(t, tx, "<code>delin=N</code>")
+	assert.Contains(t, tx, "<h2>Register Commands</h2>")
+	assert.Contains(t, tx, "<code>load=NAME:FILENAME</code>")
+	assert.NotContains(t, string(generatedSite["1.2.3/index.html"]), "tools/")
+}
+
+func TestRest(t *testing.T) {
+	old := string(generatedSite["1.2.3/rest/index.html"])
+	assert.Contains(t, old, "<td><code>/rest/old/</code><td>json<td>since <a href=/1.2.3/rest/>1.2.3</a>, removed in <a href=/2.3.4/rest/>2.3.4</a>")
+	page := string(generatedSite["2.3.4/rest/index.html"])
+	assert.Contains(t, page, "<td><code>/rest/tx/</code><td>bin, hex, json<td>since <a href=/1.2.3/rest/>1.2.3</a>")
+	assert.Contains(t, page, "<td><code>/rest/new/</code><td>json<td>since <a href=/2.3.4/rest/>2.3.4</a>")
+	assert.Contains(t, page, "<li><code>/rest/old/</code>")
+}
+
+func TestZmq(t *testing.T) {
+	page := string(generatedSite["2.3.4/zmq-notifications/index.html"])
+	assert.Contains(t, page, "Since <a href=/2.3.4/zmq-notifications/>2.3.4</a>")
+	assert.Contains(t, page, "<td><code>C</code><td>Block (C)onnect")
+	assert.Contains(t, page, "<code>pubsequence</code>, with a default high water mark of 1000 messages")
+	assert.Contains(t, string(generatedSite["2.3.4/index.html"]), "<a href=zmq-notifications/>ZMQ notifications</a>")
+}
+
+func TestRequiresWallet(t *testing.T) {
+	assert.Contains(t, string(generatedSite["2.3.4/section2/cmd3/index.html"]), "Requires a loaded wallet")
+	assert.NotContains(t, string(generatedSite["2.3.4/section2/cmd4/index.html"]), "Requires a loaded wallet")
+	assert.Contains(t, string(generatedSite["api/2.3.4/cmd3.json"]), `"requiresWallet":true`)
+}
+
+func TestPreRelease(t *testing.T) {
+	rc := version.Version{Major: 3, Pre: "rc1"}
+	db := bitcoind.NewDb(bitcoind.RpcDb{
+		version.Version{Major: 2, Minor: 3, Patch: 4}: {"section1": {{Name: "cmd1", Help: "help1"}}},
+		rc: {"section1": {{Name: "cmd1", Help: "help1"}}},
+	})
+	dbBytes, err := db.Marshal()
+	require.NoError(t, err)
+	webDir := t.TempDir()
+	require.NoError(t, gensite.Gen(dbBytes, webDir))
+	site, err := readSite(webDir)
+	require.NoError(t, err)
+
+	// the release candidate is newer, but isn't the latest release
+	assert.Contains(t, string(site["index.html"]), "Latest: <a href=2.3.4/>")
+	assert.Contains(t, string(site["3.0rc1/index.html"]), "3.0rc1 is not a final release")
+	assert.NotContains(t, string(site["2.3.4/index.html"]), "not a final release")
+}
+
+func TestPreviousRelease(t *testing.T) {
+	build := version.Version{Major: 3, Minor: 99, Label: "master-1a2b3c4"}
+	db := bitcoind.NewDb(bitcoind.RpcDb{
+		version.Version{Major: 2}:             {"section1": {{Name: "cmd1", Help: "help1"}}},
+		version.Version{Major: 3, Pre: "rc1"}: {"section1": {{Name: "cmd1", Help: "help1-rc"}}},
+		version.Version{Major: 3}:             {"section1": {{Name: "cmd1", Help: "help1-new"}}},
+		build:                                 {"section1": {{Name: "cmd1", Help: "help1-new"}}},
+	})
+	dbBytes, err := db.Marshal()
+	require.NoError(t, err)
+	webDir := t.TempDir()
+	require.NoError(t, gensite.Gen(dbBytes, webDir))
+	site, err := readSite(webDir)
+	require.NoError(t, err)
+
+	// the release is compared with the previous release, not its release candidate
+	assert.Contains(t, site, "diff/2.0..3.0/index.html")
+	assert.NotContains(t, site, "diff/3.0rc1..3.0/index.html")
+	assert.Contains(t, string(site["3.0/changes/index.html"]), `href=/diff/2.0..3.0/cmd1/`)
+	assert.Contains(t, site, "diff/2.0..3.0rc1/index.html")
+	assert.Contains(t, site, "diff/3.0..master-1a2b3c4/index.html")
+}
+
+func TestReaddedCommand(t *testing.T) {
+	db := bitcoind.NewDb(bitcoind.RpcDb{
+		version.Version{Major: 1}: {"section1": {{Name: "cmd1", Help: "help1"}, {Name: "cmd2", Help: "help2"}}},
+		version.Version{Major: 2}: {"section1": {{Name: "cmd2", Help: "help2"}}},
+		version.Version{Major: 3}: {"section1": {{Name: "cmd1", Help: "help1"}, {Name: "cmd2", Help: "help2"}}},
+	})
+	dbBytes, err := db.Marshal()
+	require.NoError(t, err)
+	webDir := t.TempDir()
+	require.NoError(t, gensite.Gen(dbBytes, webDir))
+	site, err := readSite(webDir)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(site["3.0/section1/cmd1/index.html"]),
+		`Available in <a href=/1.0/>1.0</a>, removed in <a href=/2.0/>2.0</a>, and since <a href=/3.0/>3.0</a>.`)
+	assert.Contains(t, string(site["commands/index.html"]), "<td>1.0, since 3.0")
+
+	var cmd struct {
+		Availability struct {
+			First  string `json:"first"`
+			Ranges []struct {
+				First     string `json:"first"`
+				Last      string `json:"last"`
+				RemovedIn string `json:"removedIn"`
+			} `json:"ranges"`
+		} `json:"availability"`
+	}
+	require.NoError(t, json.Unmarshal(site["api/3.0/cmd1.json"], &cmd))
+	assert.Equal(t, "1.0", cmd.Availability.First)
+	require.Len(t, cmd.Availability.Ranges, 2)
+	assert.Equal(t, "2.0", cmd.Availability.Ranges[0].RemovedIn)
+	assert.Equal(t, "3.0", cmd.Availability.Ranges[1].First)
+	assert.Empty(t, cmd.Availability.Ranges[1].RemovedIn)
+}
+
 func TestCrawl(t *testing.T) {
 	generatedHtml := make(map[string][]byte, len(generatedSite)-1)
 	for path, content := range generatedSite {
@@ -0,0 +1,189 @@
+package gensite
+
+import (
+	"bitcoinrpcschema/internal/bitcoind"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// jsonSchema is the subset of JSON Schema needed to describe RPC arguments and results
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Id                   string                 `json:"$id,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 any                    `json:"type,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	AdditionalProperties *jsonSchema            `json:"additionalProperties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	PrefixItems          []*jsonSchema          `json:"prefixItems,omitempty"`
+	MinItems             *int                   `json:"minItems,omitempty"`
+	MaxItems             *int                   `json:"maxItems,omitempty"`
+	OneOf                []*jsonSchema          `json:"oneOf,omitempty"`
+	Default              json.RawMessage        `json:"default,omitempty"`
+}
+
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// standaloneSchema marks a schema as a self-contained draft 2020-12 document published at path
+func standaloneSchema(s *jsonSchema, title, path string) ([]byte, error) {
+	id, err := url.JoinPath(canonicalHome, path)
+	if err != nil {
+		e := fmt.Errorf("failed to join schema id: %w", err)
+		return nil, e
+	}
+	doc := *s
+	doc.Schema = jsonSchemaDialect
+	doc.Id = id
+	doc.Title = title
+	return json.Marshal(doc)
+}
+
+// paramsSchema describes the positional params array of a command
+func paramsSchema(args []bitcoind.Argument) *jsonSchema {
+	s := &jsonSchema{
+		Type:        "array",
+		PrefixItems: make([]*jsonSchema, len(args)),
+		MinItems:    new(int),
+		MaxItems:    new(int),
+	}
+	for i, a := range args {
+		s.PrefixItems[i] = argumentSchema(a)
+		s.PrefixItems[i].Title = argumentName(a)
+		if a.Required {
+			*s.MinItems = i + 1
+		}
+	}
+	*s.MaxItems = len(args)
+	return s
+}
+
+// jsonSchemaType maps a help text type such as "numeric or string" to JSON Schema types.
+// Unknown types map to nil, which places no constraint on the value.
+func jsonSchemaType(helpType string) any {
+	var types []string
+	for _, t := range strings.Split(helpType, " or ") {
+		switch t {
+		case "string":
+			types = append(types, "string")
+		case "numeric", "amount":
+			types = append(types, "number")
+		case "boolean":
+			types = append(types, "boolean")
+		case "json object", "empty JSON object":
+			types = append(types, "object")
+		case "json array", "array":
+			types = append(types, "array")
+		case "json null":
+			types = append(types, "null")
+		default:
+			return nil
+		}
+	}
+	if len(types) == 1 {
+		return types[0]
+	}
+	return types
+}
+
+func argumentSchema(a bitcoind.Argument) *jsonSchema {
+	s := &jsonSchema{
+		Description: a.Description,
+		Type:        jsonSchemaType(a.Type),
+	}
+	if a.HasDefault && json.Valid([]byte(a.Default)) {
+		s.Default = json.RawMessage(a.Default)
+	}
+	switch {
+	case s.Type == "object" && a.UserKeys:
+		values := make([]*jsonSchema, len(a.Fields))
+		for i, f := range a.Fields {
+			values[i] = argumentSchema(f)
+		}
+		s.AdditionalProperties = alternatives(values)
+	case s.Type == "object":
+		for _, f := range a.Fields {
+			name := argumentName(f)
+			if name == "" {
+				continue
+			}
+			if s.Properties == nil {
+				s.Properties = make(map[string]*jsonSchema, len(a.Fields))
+			}
+			s.Properties[name] = argumentSchema(f)
+			if f.Required {
+				s.Required = append(s.Required, name)
+			}
+		}
+	case s.Type == "array":
+		items := make([]*jsonSchema, len(a.Fields))
+		for i, f := range a.Fields {
+			items[i] = argumentSchema(f)
+		}
+		s.Items = alternatives(items)
+	}
+	return s
+}
+
+func resultFieldSchema(f bitcoind.ResultField) *jsonSchema {
+	s := &jsonSchema{
+		Description: f.Description,
+		Type:        jsonSchemaType(f.Type),
+	}
+	switch s.Type {
+	case "object":
+		for _, field := range f.Fields {
+			if field.Key == "" {
+				continue
+			}
+			if s.Properties == nil {
+				s.Properties = make(map[string]*jsonSchema, len(f.Fields))
+			}
+			s.Properties[field.Key] = resultFieldSchema(field)
+			if !field.Optional {
+				s.Required = append(s.Required, field.Key)
+			}
+		}
+	case "array":
+		items := make([]*jsonSchema, len(f.Fields))
+		for i, field := range f.Fields {
+			items[i] = resultFieldSchema(field)
+		}
+		s.Items = alternatives(items)
+	}
+	return s
+}
+
+// resultsSchema describes all possible results of a command, titling each alternative with its condition
+func resultsSchema(results []bitcoind.Result) *jsonSchema {
+	schemas := make([]*jsonSchema, len(results))
+	for i, r := range results {
+		schemas[i] = resultFieldSchema(r.Value)
+		schemas[i].Title = r.Condition
+	}
+	s := alternatives(schemas)
+	if s == nil {
+		return &jsonSchema{}
+	}
+	return s
+}
+
+func alternatives(schemas []*jsonSchema) *jsonSchema {
+	switch len(schemas) {
+	case 0:
+		return nil
+	case 1:
+		return schemas[0]
+	default:
+		return &jsonSchema{OneOf: schemas}
+	}
+}
+
+// argumentName is the canonical name of an argument, dropping aliases like the "verbose" of "verbosity|verbose"
+func argumentName(a bitcoind.Argument) string {
+	name, _, _ := strings.Cut(a.Name, "|")
+	return name
+}
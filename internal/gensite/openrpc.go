@@ -0,0 +1,105 @@
+package gensite
+
+import (
+	"bitcoinrpcschema/internal/bitcoind"
+	coreversion "bitcoinrpcschema/internal/version"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const openRpcVersion = "1.3.2"
+
+type openRpcDoc struct {
+	OpenRpc string          `json:"openrpc"`
+	Info    openRpcInfo     `json:"info"`
+	Methods []openRpcMethod `json:"methods"`
+}
+
+type openRpcInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openRpcMethod struct {
+	Name           string                     `json:"name"`
+	Summary        string                     `json:"summary,omitempty"`
+	Description    string                     `json:"description,omitempty"`
+	Tags           []openRpcTag               `json:"tags"`
+	ParamStructure string                     `json:"paramStructure"`
+	Params         []openRpcContentDescriptor `json:"params"`
+	Result         openRpcContentDescriptor   `json:"result"`
+}
+
+type openRpcTag struct {
+	Name string `json:"name"`
+}
+
+type openRpcContentDescriptor struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Required    bool        `json:"required,omitempty"`
+	Schema      *jsonSchema `json:"schema"`
+}
+
+// openRpc generates the OpenRPC document describing every command of a release, tagged with its section
+func openRpc(rv coreversion.Version, sections map[string][]bitcoind.Command) ([]byte, error) {
+	doc := openRpcDoc{
+		OpenRpc: openRpcVersion,
+		Info: openRpcInfo{
+			Title:   "Bitcoin Core RPC",
+			Version: rv.String(),
+		},
+		Methods: []openRpcMethod{},
+	}
+	for sec, cmds := range sections {
+		for _, cmd := range cmds {
+			m, err := openRpcMethodOf(sec, cmd)
+			if err != nil {
+				e := fmt.Errorf("failed to describe method %s: %w", cmd.Name, err)
+				return nil, e
+			}
+			doc.Methods = append(doc.Methods, m)
+		}
+	}
+	slices.SortFunc(doc.Methods, func(a, b openRpcMethod) int {
+		if c := strings.Compare(a.Tags[0].Name, b.Tags[0].Name); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return json.Marshal(doc)
+}
+
+func openRpcMethodOf(section string, cmd bitcoind.Command) (openRpcMethod, error) {
+	desc, err := parseDescription(cmd.Help)
+	if err != nil {
+		e := fmt.Errorf("failed to parse description: %w", err)
+		return openRpcMethod{}, e
+	}
+
+	m := openRpcMethod{
+		Name:           cmd.Name,
+		Description:    strings.Join(desc.Explanation, "\n"),
+		Tags:           []openRpcTag{{Name: section}},
+		ParamStructure: "either",
+		Params:         make([]openRpcContentDescriptor, len(cmd.Arguments)),
+		Result: openRpcContentDescriptor{
+			Name:   "result",
+			Schema: resultsSchema(cmd.Results),
+		},
+	}
+	if len(desc.Explanation) > 0 {
+		m.Summary = desc.Explanation[0]
+	}
+	for i, a := range cmd.Arguments {
+		m.Params[i] = openRpcContentDescriptor{
+			Name:        argumentName(a),
+			Description: a.Description,
+			Required:    a.Required,
+			Schema:      argumentSchema(a),
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,106 @@
+package gensite
+
+import (
+	"bitcoinrpcschema/internal/bitcoind"
+	coreversion "bitcoinrpcschema/internal/version"
+	_ "embed"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+//go:embed rest.html
+var restHtml string
+
+var restTmpl = mustBtcTemplate("rest", restHtml)
+
+// restPage lists the REST endpoints of a release
+type restPage struct {
+	Version   string
+	Previous  string
+	Endpoints []restEndpoint
+	// Removed are the endpoints of the previous release that this one doesn't have
+	Removed []string
+}
+
+type restEndpoint struct {
+	bitcoind.RestEndpoint
+	Since     string
+	RemovedIn string
+}
+
+func (r *restPage) html() ([]byte, error) {
+	rendered, err := restTmpl.render(r)
+	if err != nil {
+		e := fmt.Errorf("failed to render REST html for %s: %w", r.Version, err)
+		return nil, e
+	}
+	return rendered, nil
+}
+
+// releaseAvailability computes the first version of each name listed by names, and the version it was removed in.
+// Releases for which names returns nil weren't captured, and are left out.
+func releaseAvailability(db *bitcoind.Db, names func(*bitcoind.Release) []string) map[string]*availability {
+	var versions []coreversion.Version
+	for rv, release := range db.Releases {
+		if names(release) != nil {
+			versions = append(versions, rv)
+		}
+	}
+	slices.SortFunc(versions, func(a, b coreversion.Version) int { return a.Cmp(b) })
+
+	avail := make(map[string]*availability)
+	for i, v := range versions {
+		for _, name := range names(db.Releases[v]) {
+			a, ok := avail[name]
+			if !ok {
+				a = &availability{Name: name}
+				avail[name] = a
+			}
+			a.add(versions, i)
+		}
+	}
+	return avail
+}
+
+func restPaths(release *bitcoind.Release) []string {
+	if release.Rest == nil {
+		return nil
+	}
+	paths := make([]string, len(release.Rest))
+	for i, e := range release.Rest {
+		paths[i] = e.Path
+	}
+	return paths
+}
+
+// addRestPages adds a REST page for every release with captured REST endpoints
+func addRestPages(site site, db *bitcoind.Db, previous map[coreversion.Version]coreversion.Version) error {
+	avail := releaseAvailability(db, restPaths)
+	for rv, release := range db.Releases {
+		if len(release.Rest) == 0 {
+			continue
+		}
+		r := &restPage{Version: rv.String()}
+		for _, e := range release.Rest {
+			a := avail[e.Path]
+			r.Endpoints = append(r.Endpoints, restEndpoint{RestEndpoint: e, Since: a.First(), RemovedIn: a.RemovedIn()})
+		}
+		slices.SortFunc(r.Endpoints, func(a, b restEndpoint) int {
+			return strings.Compare(a.Path, b.Path)
+		})
+		if prev, ok := previous[rv]; ok && len(db.Releases[prev].Rest) > 0 {
+			r.Previous = prev.String()
+			for _, e := range db.Releases[prev].Rest {
+				if !slices.ContainsFunc(release.Rest, func(other bitcoind.RestEndpoint) bool { return other.Path == e.Path }) {
+					r.Removed = append(r.Removed, e.Path)
+				}
+			}
+		}
+		err := site.add(rv.String()+"/rest/index.html", r)
+		if err != nil {
+			return fmt.Errorf("failed to add REST endpoints for %s to site: %w", rv, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package gensite
+
+import (
+	"bitcoinrpcschema/internal/bitcoind"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+//go:embed search.html
+var searchHtml string
+
+//go:embed search.js
+var searchJs []byte
+
+var searchTmpl = mustBtcTemplate("search", searchHtml)
+
+// searchPage is the client-side search UI, which loads one index shard per version
+type searchPage struct {
+	Versions []string
+}
+
+type searchEntry struct {
+	Name        string   `json:"name"`
+	Section     string   `json:"section"`
+	Explanation string   `json:"explanation"`
+	Arguments   []string `json:"arguments"`
+}
+
+func (s *searchPage) html() ([]byte, error) {
+	rendered, err := searchTmpl.render(s)
+	if err != nil {
+		e := fmt.Errorf("failed to render search html: %w", err)
+		return nil, e
+	}
+	return rendered, nil
+}
+
+// searchIndex builds the search index shard of a single version
+func searchIndex(sections map[string][]bitcoind.Command) ([]byte, error) {
+	entries := make([]searchEntry, 0)
+	for sec, cmds := range sections {
+		for _, cmd := range cmds {
+			desc, err := parseDescription(cmd.Help)
+			if err != nil {
+				e := fmt.Errorf("failed to parse description of %s: %w", cmd.Name, err)
+				return nil, e
+			}
+			entries = append(entries, searchEntry{
+				Name:        cmd.Name,
+				Section:     sec,
+				Explanation: strings.Join(desc.Explanation, " "),
+				Arguments:   argumentNames(cmd.Arguments),
+			})
+		}
+	}
+	slices.SortFunc(entries, func(a, b searchEntry) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return json.Marshal(entries)
+}
+
+// argumentNames lists the names of the arguments and all their named fields, without duplicates
+func argumentNames(args []bitcoind.Argument) []string {
+	names := make([]string, 0, len(args))
+	var collect func(args []bitcoind.Argument)
+	collect = func(args []bitcoind.Argument) {
+		for _, a := range args {
+			name := argumentName(a)
+			if name != "" && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+			collect(a.Fields)
+		}
+	}
+	collect(args)
+	return names
+}
@@ -0,0 +1,96 @@
+"use strict";
+
+(function () {
+    const form = document.getElementById("search");
+    const versionSelect = document.getElementById("search-version");
+    const queryInput = document.getElementById("search-query");
+    const status = document.getElementById("search-status");
+    const results = document.getElementById("search-results");
+    const shards = new Map();
+
+    const params = new URLSearchParams(window.location.search);
+    if (params.has("version")) {
+        versionSelect.value = params.get("version");
+    }
+    if (params.has("q")) {
+        queryInput.value = params.get("q");
+    }
+
+    function loadShard(version) {
+        if (!shards.has(version)) {
+            shards.set(version, fetch(version + ".json").then(function (response) {
+                if (!response.ok) {
+                    throw new Error("failed to load search index for " + version + ": " + response.status);
+                }
+                return response.json();
+            }));
+        }
+        return shards.get(version);
+    }
+
+    // score ranks name matches above argument matches above explanation matches; 0 means no match
+    function score(entry, terms) {
+        let total = 0;
+        for (const term of terms) {
+            const name = entry.name.toLowerCase();
+            if (name === term) {
+                total += 100;
+            } else if (name.includes(term)) {
+                total += 50;
+            } else if (entry.arguments.some(function (arg) { return arg.toLowerCase().includes(term); })) {
+                total += 10;
+            } else if (entry.explanation.toLowerCase().includes(term)) {
+                total += 1;
+            } else {
+                return 0;
+            }
+        }
+        return total;
+    }
+
+    function render(version, matches) {
+        results.replaceChildren();
+        for (const entry of matches) {
+            const item = document.createElement("li");
+            const link = document.createElement("a");
+            link.href = "/" + version + "/" + entry.section + "/" + entry.name + "/";
+            link.textContent = entry.name;
+            item.append(link, " (" + entry.section + ")");
+            if (entry.explanation) {
+                const explanation = document.createElement("small");
+                explanation.textContent = " " + entry.explanation;
+                item.append(explanation);
+            }
+            results.append(item);
+        }
+    }
+
+    function search() {
+        const version = versionSelect.value;
+        const terms = queryInput.value.toLowerCase().split(/\s+/).filter(Boolean);
+        if (terms.length === 0) {
+            status.textContent = "";
+            results.replaceChildren();
+            return;
+        }
+        loadShard(version).then(function (entries) {
+            const matches = entries
+                .map(function (entry) { return {entry: entry, score: score(entry, terms)}; })
+                .filter(function (m) { return m.score > 0; })
+                .sort(function (a, b) { return b.score - a.score || a.entry.name.localeCompare(b.entry.name); })
+                .map(function (m) { return m.entry; });
+            status.textContent = matches.length + " result" + (matches.length === 1 ? "" : "s") + " in " + version;
+            render(version, matches);
+        }).catch(function (err) {
+            status.textContent = err.message;
+        });
+    }
+
+    form.addEventListener("submit", function (event) {
+        event.preventDefault();
+        search();
+    });
+    queryInput.addEventListener("input", search);
+    versionSelect.addEventListener("change", search);
+    search();
+})();
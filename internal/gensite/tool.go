@@ -0,0 +1,73 @@
+package gensite
+
+import (
+	"bitcoinrpcschema/internal/bitcoind"
+	_ "embed"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+//go:embed tool.html
+var toolHtml string
+
+var toolTmpl = mustBtcTemplate("tool", toolHtml)
+
+// toolPage documents the options and commands of a tool like bitcoin-cli in a release
+type toolPage struct {
+	Version string
+	Name    string
+	bitcoind.ToolHelp
+}
+
+func (t *toolPage) html() ([]byte, error) {
+	rendered, err := toolTmpl.render(map[string]any{
+		"Version":  t.Version,
+		"Name":     t.Name,
+		"Usage":    t.Usage,
+		"Groups":   t.Groups,
+		"Commands": commandGroups(t.Commands),
+	})
+	if err != nil {
+		e := fmt.Errorf("failed to render tool html for %s %s: %w", t.Name, t.Version, err)
+		return nil, e
+	}
+	return rendered, nil
+}
+
+// addToolPages adds a page for every tool captured in every release
+func addToolPages(site site, db *bitcoind.Db) error {
+	for rv, release := range db.Releases {
+		for name, help := range release.Tools {
+			p := fmt.Sprintf("%s/tools/%s/index.html", rv, name)
+			err := site.add(p, &toolPage{Version: rv.String(), Name: name, ToolHelp: help})
+			if err != nil {
+				return fmt.Errorf("failed to add %s of %s to site: %w", name, rv, err)
+			}
+		}
+	}
+	return nil
+}
+
+func toolNames(release *bitcoind.Release) []string {
+	return slices.Sorted(maps.Keys(release.Tools))
+}
+
+// commandGroup is the commands a tool lists under one heading
+type commandGroup struct {
+	Name     string
+	Commands []bitcoind.ToolCommand
+}
+
+// commandGroups splits commands by their heading, keeping the order of the -help output
+func commandGroups(commands []bitcoind.ToolCommand) []commandGroup {
+	var groups []commandGroup
+	for _, c := range commands {
+		if len(groups) == 0 || groups[len(groups)-1].Name != c.Group {
+			groups = append(groups, commandGroup{Name: c.Group})
+		}
+		g := &groups[len(groups)-1]
+		g.Commands = append(g.Commands, c)
+	}
+	return groups
+}
@@ -12,6 +12,13 @@ var versionHtml string
 type version struct {
 	Name     string
 	Sections map[string][]string
+	Previous string
+	// PreRelease is set for release candidates and other builds than final releases
+	PreRelease bool
+	HasConfig  bool
+	HasRest    bool
+	HasZmq     bool
+	Tools      []string
 }
 
 var versionTmpl = mustBtcTemplate("version", versionHtml)
@@ -0,0 +1,75 @@
+package gensite
+
+import (
+	"bitcoinrpcschema/internal/bitcoind"
+	coreversion "bitcoinrpcschema/internal/version"
+	_ "embed"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+//go:embed zmq.html
+var zmqHtml string
+
+var zmqTmpl = mustBtcTemplate("zmq", zmqHtml)
+
+// zmqPath is the directory of the ZMQ page of a release, which mustn't clash with the zmq RPC section
+func zmqPath(rv coreversion.Version) string {
+	return rv.String() + "/zmq-notifications"
+}
+
+// zmqPage lists the ZMQ topics a release can publish
+type zmqPage struct {
+	Version string
+	Topics  []zmqTopic
+}
+
+type zmqTopic struct {
+	bitcoind.ZmqTopic
+	Since     string
+	RemovedIn string
+}
+
+func (z *zmqPage) html() ([]byte, error) {
+	rendered, err := zmqTmpl.render(z)
+	if err != nil {
+		e := fmt.Errorf("failed to render ZMQ html for %s: %w", z.Version, err)
+		return nil, e
+	}
+	return rendered, nil
+}
+
+func zmqTopicNames(release *bitcoind.Release) []string {
+	if release.Zmq == nil {
+		return nil
+	}
+	names := make([]string, len(release.Zmq))
+	for i, t := range release.Zmq {
+		names[i] = t.Topic
+	}
+	return names
+}
+
+// addZmqPages adds a ZMQ page for every release whose ZMQ topics were captured
+func addZmqPages(site site, db *bitcoind.Db) error {
+	avail := releaseAvailability(db, zmqTopicNames)
+	for rv, release := range db.Releases {
+		if release.Zmq == nil {
+			continue
+		}
+		z := &zmqPage{Version: rv.String()}
+		for _, t := range release.Zmq {
+			a := avail[t.Topic]
+			z.Topics = append(z.Topics, zmqTopic{ZmqTopic: t, Since: a.First(), RemovedIn: a.RemovedIn()})
+		}
+		slices.SortFunc(z.Topics, func(a, b zmqTopic) int {
+			return strings.Compare(a.Topic, b.Topic)
+		})
+		err := site.add(zmqPath(rv)+"/index.html", z)
+		if err != nil {
+			return fmt.Errorf("failed to add ZMQ topics for %s to site: %w", rv, err)
+		}
+	}
+	return nil
+}
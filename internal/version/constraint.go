@@ -0,0 +1,75 @@
+package version
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Constraint is a list of bounds that versions must all be within
+type Constraint []bound
+
+type bound struct {
+	op    string
+	parts []uint
+}
+
+var boundRe = regexp.MustCompile(`^(>=|<=|>|<|=)?(\d+(?:\.\d+){0,2})$`)
+
+// ParseConstraint parses space separated bounds, like ">=22 <28". A bound only compares the parts
+// of the version it has, so "<=27" includes 27.2, and a bound without operator is an equality.
+func ParseConstraint(s string) (Constraint, error) {
+	var c Constraint
+	for _, b := range strings.Fields(s) {
+		matches := boundRe.FindStringSubmatch(b)
+		if matches == nil {
+			return nil, fmt.Errorf("invalid version bound %q, expected e.g. >=22 or <0.21", b)
+		}
+		parsed := bound{op: matches[1]}
+		for _, part := range strings.Split(matches[2], ".") {
+			n, err := strconv.ParseUint(part, 10, 0)
+			if err != nil {
+				return nil, fmt.Errorf("invalid version bound %q: %w", b, err)
+			}
+			parsed.parts = append(parsed.parts, uint(n))
+		}
+		c = append(c, parsed)
+	}
+	return c, nil
+}
+
+// Matches tells whether v is within every bound
+func (c Constraint) Matches(v Version) bool {
+	for _, b := range c {
+		if !b.holds(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (b bound) holds(v Version) bool {
+	c := 0
+	for i, part := range []uint{v.Major, v.Minor, v.Patch}[:len(b.parts)] {
+		if part != b.parts[i] {
+			c = 1
+			if part < b.parts[i] {
+				c = -1
+			}
+			break
+		}
+	}
+	switch b.op {
+	case ">=":
+		return c >= 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case "<":
+		return c < 0
+	default:
+		return c == 0
+	}
+}
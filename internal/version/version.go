@@ -0,0 +1,211 @@
+// Package version parses and orders Bitcoin Core versions, from release directories, git tags, tarballs
+// and what bitcoind reports.
+package version
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// LabelFile marks a directory built from a git ref, and holds its Label
+const LabelFile = "label"
+
+// Version is a Bitcoin Core version, or a build from a git ref
+type Version struct {
+	Major uint `json:"major"`
+	Minor uint `json:"minor"`
+	Patch uint `json:"patch"`
+	// Pre is the pre-release tag, like rc1
+	Pre string `json:"pre,omitempty"`
+	// Suffix follows the version in non-release builds, like .knots20240801 or -1a2b3c4 for a dev build
+	Suffix string `json:"suffix,omitempty"`
+	// Label names a build from a git ref, like master-1a2b3c4, instead of its version number
+	Label string `json:"label,omitempty"`
+}
+
+// String formats v like its release is named: 0.x releases always have a patch number, like 0.21.0,
+// later ones only when it isn't 0, like 27.0 and 27.1.1
+func (v Version) String() string {
+	if v.Label != "" {
+		return v.Label
+	}
+	s := fmt.Sprintf("%d.%d", v.Major, v.Minor)
+	if v.Patch != 0 || v.Major == 0 {
+		s = fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	}
+	return s + v.Pre + v.Suffix
+}
+
+// IsRelease tells whether v is a final release, rather than a pre-release or another build
+func (v Version) IsRelease() bool {
+	return v.Pre == "" && v.Suffix == "" && v.Label == ""
+}
+
+// RC is the release candidate number, 0 if v isn't one
+func (v Version) RC() uint {
+	n, err := strconv.ParseUint(strings.TrimPrefix(v.Pre, "rc"), 10, 0)
+	if err != nil || !strings.HasPrefix(v.Pre, "rc") {
+		return 0
+	}
+	return uint(n)
+}
+
+// Series is the major version of a release: 0.x before v22
+func (v Version) Series() Version {
+	if v.Major == 0 {
+		return Version{Minor: v.Minor}
+	}
+	return Version{Major: v.Major}
+}
+
+// Cmp orders versions by number. Pre-releases come before their release, and other builds after it.
+func (v Version) Cmp(other Version) int {
+	if c := cmp.Compare(v.Major, other.Major); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.Minor, other.Minor); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.Patch, other.Patch); c != 0 {
+		return c
+	}
+	if v.Pre != other.Pre {
+		if v.Pre == "" {
+			return 1
+		}
+		if other.Pre == "" {
+			return -1
+		}
+		return cmpNatural(v.Pre, other.Pre)
+	}
+	if c := cmpNatural(v.Suffix, other.Suffix); c != 0 {
+		return c
+	}
+	return strings.Compare(v.Label, other.Label)
+}
+
+var digitsRe = regexp.MustCompile(`\d+|\D+`)
+
+// cmpNatural compares strings with their runs of digits compared as numbers, so that rc2 < rc10
+func cmpNatural(a, b string) int {
+	as, bs := digitsRe.FindAllString(a, -1), digitsRe.FindAllString(b, -1)
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.ParseUint(as[i], 10, 64)
+		bn, bErr := strconv.ParseUint(bs[i], 10, 64)
+		if aErr == nil && bErr == nil {
+			if c := cmp.Compare(an, bn); c != 0 {
+				return c
+			}
+			continue
+		}
+		if c := strings.Compare(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(as), len(bs))
+}
+
+const versionPattern = `(\d+)\.(\d+)(?:\.(\d+))?((?:alpha|beta|rc)\d+)?`
+
+var versionRe = regexp.MustCompile(`^` + versionPattern + `([.-]\S+)?$`)
+
+// Parse parses a version as String formats it, like 0.21.2, 27.0rc1 or 27.1.knots20240801
+func Parse(s string) (Version, error) {
+	matches := versionRe.FindStringSubmatch(s)
+	if matches == nil {
+		return Version{}, fmt.Errorf("invalid version %q", s)
+	}
+	return fromMatches(matches)
+}
+
+func fromMatches(matches []string) (v Version, err error) {
+	parts := []*uint{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range parts {
+		if matches[i+1] == "" {
+			continue
+		}
+		n, err := strconv.ParseUint(matches[i+1], 10, 0)
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid version number %s: %w", matches[i+1], err)
+		}
+		*part = uint(n)
+	}
+	v.Pre = matches[4]
+	if len(matches) > 5 {
+		v.Suffix = matches[5]
+	}
+	return v, nil
+}
+
+// Tag is the git tag of a release
+func (v Version) Tag() string {
+	return "v" + v.String()
+}
+
+// ParseTag parses a git tag, like v27.0rc1
+func ParseTag(tag string) (Version, error) {
+	s, ok := strings.CutPrefix(tag, "v")
+	if !ok {
+		return Version{}, fmt.Errorf("invalid version tag %q", tag)
+	}
+	return Parse(s)
+}
+
+// ParseDir parses the directory of a release: bitcoin-core-27.0/ on the download server,
+// or bitcoin-27.0rc1 once extracted
+func ParseDir(dir string) (Version, error) {
+	name := strings.TrimSuffix(dir, "/")
+	s, ok := strings.CutPrefix(name, "bitcoin-core-")
+	if !ok {
+		s, ok = strings.CutPrefix(name, "bitcoin-")
+	}
+	if !ok {
+		return Version{}, fmt.Errorf("invalid release directory %q", dir)
+	}
+	return Parse(s)
+}
+
+// Tarball is the name of the release tarball for a platform
+func (v Version) Tarball(platform string) string {
+	return fmt.Sprintf("bitcoin-%s-%s.tar.gz", v, platform)
+}
+
+var tarballRe = regexp.MustCompile(`^bitcoin-` + versionPattern + `-(.+)\.tar\.gz$`)
+
+// ParseTarball parses the name of a release tarball, like bitcoin-27.0rc1-x86_64-linux-gnu.tar.gz,
+// into its version and platform
+func ParseTarball(name string) (Version, string, error) {
+	matches := tarballRe.FindStringSubmatch(name)
+	if matches == nil {
+		return Version{}, "", fmt.Errorf("invalid tarball name %q", name)
+	}
+	v, err := fromMatches(matches[:5])
+	return v, matches[5], err
+}
+
+var subversionRe = regexp.MustCompile(`(\d+)\.(\d+)\.(\d+)`)
+
+// ParseSubversion parses the user agent of a node, like /Satoshi:27.1.0/. It only has version numbers.
+func ParseSubversion(subversion string) (Version, error) {
+	matches := subversionRe.FindStringSubmatch(subversion)
+	if matches == nil {
+		return Version{}, fmt.Errorf("could not parse version %s", subversion)
+	}
+	return fromMatches(append(matches, ""))
+}
+
+var fullVersionRe = regexp.MustCompile(`version v` + versionPattern + `(\S*)`)
+
+// ParseFull parses the output of bitcoind -version, which unlike the subversion has pre-release and suffix tags,
+// like v28.0rc1, v27.1.knots20240801 or v29.99.0-1a2b3c4
+func ParseFull(s string) (Version, error) {
+	matches := fullVersionRe.FindStringSubmatch(s)
+	if matches == nil {
+		line, _, _ := strings.Cut(s, "\n")
+		return Version{}, fmt.Errorf("could not find version in %q", line)
+	}
+	return fromMatches(matches)
+}
@@ -0,0 +1,116 @@
+package version
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"slices"
+	"testing"
+)
+
+func TestOrder(t *testing.T) {
+	ordered := []Version{
+		{Major: 0, Minor: 21, Patch: 2},
+		{Major: 27, Minor: 1},
+		{Major: 27, Minor: 1, Suffix: ".knots20240801"},
+		{Major: 28, Pre: "rc2"},
+		{Major: 28, Pre: "rc10"},
+		{Major: 28},
+		{Major: 28, Minor: 1},
+		{Major: 29, Minor: 99, Label: "master-1a2b3c4"},
+		{Major: 29, Minor: 99, Suffix: "-1a2b3c4"},
+	}
+	shuffled := slices.Clone(ordered)
+	slices.Reverse(shuffled)
+	slices.SortFunc(shuffled, Version.Cmp)
+	assert.Equal(t, ordered, shuffled)
+
+	var names []string
+	for _, v := range ordered {
+		names = append(names, v.String())
+	}
+	assert.Equal(t, []string{"0.21.2", "27.1", "27.1.knots20240801", "28.0rc2", "28.0rc10", "28.0", "28.1",
+		"master-1a2b3c4", "29.99-1a2b3c4"}, names)
+
+	// the difference of uints used to wrap around
+	assert.Equal(t, -1, Version{Major: 1}.Cmp(Version{Major: 1 << 63}))
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		parse    func(string) (Version, error)
+		s        string
+		expected Version
+	}{
+		{Parse, "0.21.2", Version{Minor: 21, Patch: 2}},
+		{ParseTag, "v28.0rc2", Version{Major: 28, Pre: "rc2"}},
+		{Parse, "27.0rc1", Version{Major: 27, Pre: "rc1"}},
+		{Parse, "27.1.knots20240801", Version{Major: 27, Minor: 1, Suffix: ".knots20240801"}},
+		{ParseDir, "bitcoin-core-27.1/", Version{Major: 27, Minor: 1}},
+		{ParseDir, "bitcoin-28.0rc1", Version{Major: 28, Pre: "rc1"}},
+		{ParseSubversion, "/Satoshi:27.1.0/Knots:20240801/", Version{Major: 27, Minor: 1}},
+		{ParseFull, "Bitcoin Core version v28.0rc1\nCopyright (C) 2009-2024", Version{Major: 28, Pre: "rc1"}},
+		{ParseFull, "Bitcoin Core Daemon version v0.21.2\n", Version{Minor: 21, Patch: 2}},
+		{ParseFull, "Bitcoin Knots version v27.1.knots20240801\n", Version{Major: 27, Minor: 1, Suffix: ".knots20240801"}},
+		{ParseFull, "Bitcoin Core version v29.99.0-1a2b3c4-dirty\n", Version{Major: 29, Minor: 99, Suffix: "-1a2b3c4-dirty"}},
+	}
+	for _, test := range tests {
+		v, err := test.parse(test.s)
+		require.NoError(t, err, test.s)
+		assert.Equal(t, test.expected, v, test.s)
+	}
+
+	for _, invalid := range []string{"27", "v27.0", "test.rc1", "master-1a2b3c4"} {
+		_, err := Parse(invalid)
+		assert.Error(t, err, invalid)
+	}
+	_, err := ParseDir("../")
+	assert.Error(t, err)
+	_, err = ParseFull("Usage: bitcoind [options]")
+	assert.Error(t, err)
+}
+
+func TestTarball(t *testing.T) {
+	for _, v := range []Version{{Major: 27, Pre: "rc1"}, {Major: 27, Minor: 1, Patch: 1}, {Minor: 21}} {
+		name := v.Tarball("x86_64-linux-gnu")
+		parsed, platform, err := ParseTarball(name)
+		require.NoError(t, err)
+		assert.Equal(t, v, parsed, name)
+		assert.Equal(t, "x86_64-linux-gnu", platform)
+
+		parsed, err = ParseTag(v.Tag())
+		require.NoError(t, err)
+		assert.Equal(t, v, parsed, v.Tag())
+	}
+	assert.Equal(t, "bitcoin-27.0rc1-x86_64-linux-gnu.tar.gz", Version{Major: 27, Pre: "rc1"}.Tarball("x86_64-linux-gnu"))
+	// 0.x releases keep a zero patch
+	assert.Equal(t, "bitcoin-0.21.0-x86_64-linux-gnu.tar.gz", Version{Minor: 21}.Tarball("x86_64-linux-gnu"))
+	assert.Equal(t, "v0.21.0", Version{Minor: 21}.Tag())
+
+	_, _, err := ParseTarball("bitcoin-27.0-win64.zip")
+	assert.Error(t, err)
+	_, err = ParseTag("27.0")
+	assert.Error(t, err)
+}
+
+func TestRC(t *testing.T) {
+	assert.Equal(t, uint(2), Version{Major: 28, Pre: "rc2"}.RC())
+	assert.Equal(t, uint(0), Version{Major: 28}.RC())
+	assert.Equal(t, uint(0), Version{Major: 28, Pre: "beta1"}.RC())
+}
+
+func TestConstraint(t *testing.T) {
+	c, err := ParseConstraint(">=22 <=27")
+	require.NoError(t, err)
+	assert.True(t, c.Matches(Version{Major: 22}))
+	assert.True(t, c.Matches(Version{Major: 27, Minor: 2}))
+	assert.False(t, c.Matches(Version{Major: 28, Pre: "rc1"}))
+	assert.False(t, c.Matches(Version{Minor: 21, Patch: 2}))
+
+	c, err = ParseConstraint("0.21")
+	require.NoError(t, err)
+	assert.True(t, c.Matches(Version{Minor: 21, Patch: 2}))
+	assert.False(t, c.Matches(Version{Minor: 20}))
+
+	_, err = ParseConstraint("~22")
+	assert.Error(t, err)
+}